@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"standardizer/config"
+	"standardizer/consumer"
+	"standardizer/controllers"
+	"standardizer/migrations"
+	"standardizer/models"
+	"standardizer/queue"
+	"standardizer/repository"
+	"standardizer/router"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// 应用容器：在 main 中按配置构建一次，将数据库、缓存、队列、LLM 服务和分析器
+// 注入 HTTP 处理器和扫描任务消费者
+type App struct {
+	Ctx       context.Context
+	DB        *gorm.DB
+	Redis     *redis.Client
+	Queue     *queue.RabbitMQ
+	Providers *models.ProviderPool
+	Repos     *repository.Repositories
+	Analyzer  *models.CodeAnalyzer
+	Progress  *models.ProgressHub
+	Worker    *consumer.Worker
+	Router    *gin.Engine
+}
+
+// 连接外部服务并装配各组件，调用前须已读取配置。出错时关闭已建立的连接
+func New(ctx context.Context) (_ *App, err error) {
+	a := &App{Ctx: ctx, Progress: models.NewProgressHub()}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	if a.DB, err = config.OpenDB(); err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	// 数据库结构落后时拒绝启动，需先运行 migrate 子命令
+	if err := migrations.Check(a.DB); err != nil {
+		return nil, err
+	}
+	a.Repos = repository.New(a.DB)
+	if a.Redis, err = config.NewRedis(); err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	if a.Queue, err = config.DialRabbitMQ(); err != nil {
+		return nil, fmt.Errorf("连接 RabbitMQ 失败: %w", err)
+	}
+	a.Providers = config.NewProviderPool(a.Redis)
+	if a.Analyzer, err = config.NewCodeAnalyzer(ctx, a.Providers); err != nil {
+		return nil, fmt.Errorf("创建代码分析器失败: %w", err)
+	}
+
+	a.Worker = &consumer.Worker{
+		Ctx:         ctx,
+		Jobs:        a.Repos.Jobs,
+		Reports:     a.Repos.Reports,
+		Findings:    a.Repos.Findings,
+		Calls:       a.Repos.Calls,
+		Queue:       a.Queue,
+		NewAnalyzer: a.NewAnalyzer,
+		Providers:   a.Providers,
+		Progress:    a.Progress,
+	}
+	a.Router = router.SetupRouter(a.Handlers())
+	return a, nil
+}
+
+// 以配置好的分析器为模板，为一个扫描任务创建分析器，并按任务分配提示词模板版本
+func (a *App) NewAnalyzer(job *models.ScanJob, onFinding func(models.Issue), onCall func(*models.LLMCall)) (consumer.Analyzer, error) {
+	analyzer := a.Analyzer.NewScan()
+	versions, err := analyzer.Prompts.Assign(strconv.FormatUint(uint64(job.ID), 10), job.Prompts)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > 0 {
+		slog.Info("扫描任务使用的提示词模板版本", "job_id", job.ID, "versions", versions)
+	}
+	analyzer.PromptVersions = versions
+	analyzer.OnFinding, analyzer.OnCall = onFinding, onCall
+	return analyzer, nil
+}
+
+// 构建 HTTP 处理器
+func (a *App) Handlers() *controllers.Handlers {
+	return &controllers.Handlers{
+		Auth:         &controllers.AuthHandler{Users: a.Repos.Users},
+		Article:      &controllers.ArticleHandler{Articles: a.Repos.Articles, Cache: &controllers.RedisCache{Client: a.Redis}},
+		ExchangeRate: &controllers.ExchangeRateHandler{Rates: a.Repos.Rates},
+		Scan: &controllers.ScanHandler{
+			Jobs:      a.Repos.Jobs,
+			Reports:   a.Repos.Reports,
+			Findings:  a.Repos.Findings,
+			Calls:     a.Repos.Calls,
+			Queue:     a.Queue,
+			Providers: a.Providers,
+			Progress:  a.Progress,
+			Prompts:   a.Analyzer.Prompts,
+		},
+		Report: &controllers.ReportHandler{
+			Jobs:     a.Repos.Jobs,
+			Reports:  a.Repos.Reports,
+			Findings: a.Repos.Findings,
+			Prompts:  a.Analyzer.Prompts,
+		},
+	}
+}
+
+// 启动 LLM 服务健康检查和扫描任务消费者
+func (a *App) Start() {
+	a.Providers.Start(a.Ctx)
+	a.Worker.Start()
+}
+
+// 关闭外部连接
+func (a *App) Close() {
+	if a.Queue != nil {
+		if err := a.Queue.Close(); err != nil {
+			slog.Error("关闭 RabbitMQ 连接失败", "error", err)
+		}
+	}
+	if a.Redis != nil {
+		a.Redis.Close()
+	}
+	if a.DB != nil {
+		if sqlDB, err := a.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"standardizer/config"
+	"standardizer/models"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 默认语料录制的 LLM 响应，修改提示词、规则或语料后需用 -record 重新录制
+const defaultRecording = "./benchmark/recording.json"
+
+// 评测命令：用标注了预期问题的语料评估分析器的精确率和召回率。
+// 默认从录制文件重放 LLM 响应，-live 或 -record 时调用配置的 LLM
+//
+//	go run . benchmark -corpus ./benchmark/corpus [-replay llm.json | -record llm.json | -live] [-out result.json] [-v]
+func Run(args []string) error {
+	fs := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	corpus := fs.String("corpus", "./benchmark/corpus", "语料目录")
+	out := fs.String("out", "", "评测结果文件，默认为语料目录下的 .benchmark.json，同时作为上次评测结果读取")
+	record := fs.String("record", "", "调用配置的 LLM 并将响应录制到该文件")
+	replay := fs.String("replay", defaultRecording, "从录制文件重放 LLM 响应，不连接 LLM 服务")
+	live := fs.Bool("live", false, "直接调用配置的 LLM，不重放也不录制")
+	verbose := fs.Bool("v", false, "列出所有误报和漏报")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["replay"] && (*record != "" || *live) {
+		return errors.New("-replay 不能与 -record 或 -live 同时使用")
+	}
+	if *record != "" || *live {
+		*replay = ""
+	}
+	if *out == "" {
+		*out = filepath.Join(*corpus, ".benchmark.json")
+	}
+
+	config.LoadConfig()
+	analyzer, err := config.NewCodeAnalyzer(context.Background(), config.NewProviderPool(nil))
+	if err != nil {
+		return err
+	}
+	// 只评测检查结果，不生成修复
+	analyzer.AutoFix, analyzer.VerifyFixes = false, false
+
+	var recording *Recording
+	if path := *record + *replay; path != "" {
+		if recording, err = OpenRecording(path); err != nil {
+			return err
+		}
+		wrap := func(name string, inner llms.Model) llms.Model {
+			if *replay != "" {
+				inner = nil
+			}
+			return recording.Model(name, inner)
+		}
+		analyzer.Llm = wrap("default", analyzer.Llm)
+		for i, voter := range analyzer.Consensus.Voters {
+			analyzer.Consensus.Voters[i].Model = wrap(voter.Name, voter.Model)
+		}
+		if retriever := analyzer.Retriever; retriever != nil {
+			inner := retriever.Client
+			if *replay != "" {
+				inner = nil
+			}
+			retriever.Client = recording.Embedder(retriever.Model, inner)
+		}
+	}
+
+	result, err := evaluate(analyzer, *corpus)
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		if err := recording.Save(); err != nil {
+			return err
+		}
+	}
+	if *replay != "" && recording.Misses() > 0 {
+		return fmt.Errorf("录制文件 %s 中缺少 %d 次调用的响应，请用 -record 重新录制或使用 -live", *replay, recording.Misses())
+	}
+
+	previous, err := loadResult(*out)
+	if err != nil {
+		slog.Warn("读取上次评测结果失败", "file", *out, "error", err)
+	}
+	printResult(os.Stdout, result, previous, *verbose)
+	return saveResult(*out, result)
+}
+
+// 扫描语料并与预期问题比对
+func evaluate(analyzer *models.CodeAnalyzer, corpus string) (*Result, error) {
+	project, err := models.LoadProjectConfig(corpus)
+	if err != nil {
+		return nil, err
+	}
+	// 分析去掉预期标记的副本，路径和行号与原语料一致
+	dir, err := os.MkdirTemp("", "benchmark-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	files, expected, err := loadCorpus(corpus, dir, project)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("语料目录 %s 中没有可扫描的文件", corpus)
+	}
+	project.Root = dir
+
+	analyzer.ClearAnalyzerResults()
+	if err := analyzer.UseProject(project); err != nil {
+		return nil, err
+	}
+	analyzer.Index = models.BuildProjectIndex(dir, files)
+	for _, file := range files {
+		if err := analyzer.ProcessFile(file); err != nil {
+			return nil, err
+		}
+	}
+	report := analyzer.GenerateReport(dir)
+
+	var found []Location
+	for _, f := range report.Findings {
+		found = append(found, Location{File: relPath(dir, f.File), Line: f.Line, Rule: f.Rule})
+	}
+	rules, total, mismatches := score(expected, found)
+
+	result := &Result{
+		Time:       time.Now(),
+		Corpus:     corpus,
+		Prompts:    report.Prompts,
+		Files:      len(files),
+		Rules:      rules,
+		Total:      total,
+		Mismatches: mismatches,
+	}
+	for _, voter := range analyzer.Consensus.Voters {
+		result.Models = append(result.Models, voter.Name)
+	}
+	if len(result.Models) == 0 {
+		result.Models = []string{"default"}
+	}
+	return result, nil
+}
+
+// 读取上次评测结果，文件不存在时返回 nil
+func loadResult(path string) (*Result, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func saveResult(path string, result *Result) error {
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
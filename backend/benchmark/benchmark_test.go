@@ -0,0 +1,164 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"standardizer/config"
+	"standardizer/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestStripExpectations(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"行尾标记", "    return (double)sum / count; // expect: 规则2", "    return (double)sum / count;"},
+		{"单独成行的标记", "    // expect: 规则2", ""},
+		{"Python 注释", "def load(path, cache={}):  # expect: 规则5", "def load(path, cache={}):"},
+		{"多条规则", "int *p; /* expect: 规则1, 规则3 */", "int *p;"},
+		{"普通注释保留", "int x = 0; // 计数", "int x = 0; // 计数"},
+		{"没有规则编号", "// expect: nothing", "// expect: nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripExpectations([]string{tt.line})
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCorpusKeepsLineNumbers(t *testing.T) {
+	dest := t.TempDir()
+	project, err := models.LoadProjectConfig("./corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, expected, err := loadCorpus("./corpus", dest, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 || len(expected) != 7 {
+		t.Fatalf("got %d files and %d expectations, want 4 and 7", len(files), len(expected))
+	}
+	for _, file := range files {
+		copied, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original, err := os.ReadFile(filepath.Join("./corpus", filepath.Base(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(copied), "expect:") {
+			t.Errorf("%s still contains expectation markers", file)
+		}
+		if strings.Count(string(copied), "\n") != strings.Count(string(original), "\n") {
+			t.Errorf("%s line count changed", file)
+		}
+	}
+	// 预期问题仍指向原文件中的位置
+	for _, loc := range expected {
+		if loc.File == "casts.cpp" && loc.Line == 13 && loc.Rule == "规则2" {
+			return
+		}
+	}
+	t.Errorf("expectations %v missing casts.cpp:13 规则2", expected)
+}
+
+// 记录提示词并回答没有问题的模型
+type promptRecorder struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (m *promptRecorder) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.mu.Lock()
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				m.prompts = append(m.prompts, text.Text)
+			}
+		}
+	}
+	m.mu.Unlock()
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "OK"}}}, nil
+}
+
+func (m *promptRecorder) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestEvaluatePromptsHaveNoMarkers(t *testing.T) {
+	t.Chdir("..") // 按 go run . benchmark 的工作目录读取配置
+	config.LoadConfig()
+	analyzer, err := config.NewCodeAnalyzer(context.Background(), config.NewProviderPool(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer.AutoFix, analyzer.VerifyFixes = false, false
+	model := &promptRecorder{}
+	analyzer.Llm = model
+	for i := range analyzer.Consensus.Voters {
+		analyzer.Consensus.Voters[i].Model = model
+	}
+
+	if _, err := evaluate(analyzer, "./benchmark/corpus"); err != nil {
+		t.Fatal(err)
+	}
+	if len(model.prompts) == 0 {
+		t.Fatal("no prompts sent")
+	}
+	for _, prompt := range model.prompts {
+		if strings.Contains(prompt, "expect:") {
+			t.Fatalf("prompt contains expectation marker:\n%s", prompt)
+		}
+	}
+}
+
+func TestRunReplaysDefaultRecording(t *testing.T) {
+	t.Chdir("..")
+	out := filepath.Join(t.TempDir(), "result.json")
+	if err := Run([]string{"-out", out}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result Result
+	if err := json.Unmarshal(content, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total.TP != 7 || result.Total.FP != 1 || result.Total.FN != 0 {
+		t.Errorf("got total %+v, want tp=7 fp=1 fn=0", result.Total)
+	}
+	if len(result.Mismatches) != 1 || result.Mismatches[0].Location != (Location{File: "indexes.cpp", Line: 13, Rule: "规则1"}) {
+		t.Errorf("got mismatches %+v", result.Mismatches)
+	}
+}
+
+func TestRunReplayMissingResponses(t *testing.T) {
+	t.Chdir("..")
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte(`{"responses": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Run([]string{"-replay", empty, "-out", filepath.Join(dir, "result.json")})
+	if err == nil || !strings.Contains(err.Error(), "缺少") {
+		t.Errorf("got %v, want missing responses error", err)
+	}
+	if err := Run([]string{"-replay", empty, "-live"}); err == nil {
+		t.Error("-replay with -live accepted")
+	}
+}
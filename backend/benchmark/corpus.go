@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"standardizer/models"
+	"strings"
+)
+
+// 预期问题标记，如 "// expect: 规则2" 或 "# expect: 规则4,规则5"。
+// 标记写在代码行尾时指向该行，单独成行时指向下一行。
+var expectRe = regexp.MustCompile(`(?://|/\*|#)\s*expect:\s*(规则\d+(?:\s*,\s*规则\d+)*)`)
+
+// 语料中的一个问题位置
+type Location struct {
+	File string `json:"file"` // 相对语料目录的路径
+	Line int    `json:"line"`
+	Rule string `json:"rule"`
+}
+
+// 读取文件中的预期问题
+func parseExpectations(rel string, lines []string) []Location {
+	var expected []Location
+	for i, line := range lines {
+		loc := expectRe.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		target := i + 1
+		if strings.TrimSpace(line[:loc[0]]) == "" {
+			target = i + 2
+		}
+		for _, rule := range strings.Split(line[loc[2]:loc[3]], ",") {
+			expected = append(expected, Location{File: rel, Line: target, Rule: strings.TrimSpace(rule)})
+		}
+	}
+	return expected
+}
+
+// 去掉预期问题标记，单独成行的标记替换为空行，使行号保持不变
+func stripExpectations(lines []string) []string {
+	stripped := make([]string, len(lines))
+	for i, line := range lines {
+		if loc := expectRe.FindStringIndex(line); loc != nil {
+			line = strings.TrimRight(line[:loc[0]], " \t")
+		}
+		stripped[i] = line
+	}
+	return stripped
+}
+
+// 将语料目录复制到 dest，并去掉预期问题标记，避免标记出现在提示词中提示模型。
+// 返回 dest 中按项目配置需要扫描的文件，以及从原文件中读取的预期问题
+func loadCorpus(root, dest string, project *models.ProjectConfig) ([]string, []Location, error) {
+	var files []string
+	var expected []Location
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := relPath(root, path)
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(content), "\n")
+		if err := os.WriteFile(target, []byte(strings.Join(stripExpectations(lines), "\n")), 0644); err != nil {
+			return err
+		}
+		if project.Accepts(path, models.DetectLanguage(path)) {
+			files = append(files, target)
+			expected = append(expected, parseExpectations(rel, lines)...)
+		}
+		return nil
+	})
+	return files, expected, err
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
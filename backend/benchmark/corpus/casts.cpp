@@ -0,0 +1,15 @@
+#include <cstddef>
+
+double average(const int *values, size_t count) {
+    long sum = 0;
+    for (size_t i = 0; i < count; i++) {
+        sum += values[i];
+    }
+    return (double)sum / count; // expect: 规则2
+}
+
+int truncate(double value) {
+    // expect: 规则2
+    int whole = (int)value;
+    return static_cast<int>(whole);
+}
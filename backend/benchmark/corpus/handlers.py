@@ -0,0 +1,17 @@
+def load(path, cache={}):  # expect: 规则5
+    if path in cache:
+        return cache[path]
+    try:
+        with open(path) as f:
+            cache[path] = f.read()
+    # expect: 规则4
+    except:
+        return None
+    return cache[path]
+
+
+def parse(text, sep=None):
+    try:
+        return int(text)
+    except ValueError:
+        return None
@@ -0,0 +1,14 @@
+#include <cstddef>
+
+int sum(const int values[], size_t count) {
+    int total = 0;
+    for (int i = 0; i < static_cast<int>(count); i++) {
+        total += values[i]; // expect: 规则1
+    }
+    return total;
+}
+
+int last(const int values[], size_t count) {
+    size_t index = count - 1;
+    return values[index];
+}
@@ -0,0 +1,22 @@
+#include <cstring>
+
+struct Node {
+    Node *next = nullptr;
+    int value = 0;
+};
+
+int length(const char *text) {
+    const char *cursor; // expect: 规则3
+    cursor = text;
+    return static_cast<int>(std::strlen(cursor));
+}
+
+Node *append(Node *head, Node *node) {
+    Node *tail; // expect: 规则3
+    tail = head;
+    while (tail->next != nullptr) {
+        tail = tail->next;
+    }
+    tail->next = node;
+    return head;
+}
@@ -0,0 +1,176 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 录制的 LLM 响应，按模型名称和提示词索引。录制后可在没有 LLM 服务的环境中重放，
+// 使评测结果只受提示词和解析逻辑影响
+type Recording struct {
+	Responses map[string][]string `json:"responses"`
+	// 嵌入向量，按模型名称和文本索引
+	Embeddings map[string][]float32 `json:"embeddings,omitempty"`
+
+	path   string
+	mu     sync.Mutex
+	next   map[string]int // 重放时每个提示词下一个响应的位置
+	misses int            // 重放时录制中没有响应的调用次数
+}
+
+// 打开录制文件，文件不存在时返回空录制
+func OpenRecording(path string) (*Recording, error) {
+	r := &Recording{
+		Responses:  make(map[string][]string),
+		Embeddings: make(map[string][]float32),
+		path:       path,
+		next:       make(map[string]int),
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, r); err != nil {
+		return nil, fmt.Errorf("解析录制文件 %s 失败: %w", path, err)
+	}
+	if r.Responses == nil {
+		r.Responses = make(map[string][]string)
+	}
+	if r.Embeddings == nil {
+		r.Embeddings = make(map[string][]float32)
+	}
+	return r, nil
+}
+
+// 保存录制文件
+func (r *Recording) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, content, 0644)
+}
+
+// 包装模型：inner 不为空时调用 inner 并录制响应，为空时从录制中重放
+func (r *Recording) Model(name string, inner llms.Model) llms.Model {
+	return &recordedModel{recording: r, name: name, inner: inner}
+}
+
+func recordingKey(name, prompt string) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *Recording) record(key, response string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Responses[key] = append(r.Responses[key], response)
+}
+
+// 重放时录制中没有响应的调用次数
+func (r *Recording) Misses() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.misses
+}
+
+// 依次返回同一提示词录制的响应，用完后从头开始
+func (r *Recording) replay(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	responses := r.Responses[key]
+	if len(responses) == 0 {
+		r.misses++
+		return "", false
+	}
+	i := r.next[key]
+	r.next[key] = i + 1
+	return responses[i%len(responses)], true
+}
+
+type recordedModel struct {
+	recording *Recording
+	name      string
+	inner     llms.Model
+}
+
+func (m *recordedModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var parts []string
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				parts = append(parts, text.Text)
+			}
+		}
+	}
+	key := recordingKey(m.name, strings.Join(parts, "\n"))
+
+	if m.inner == nil {
+		response, ok := m.recording.replay(key)
+		if !ok {
+			return nil, fmt.Errorf("录制中没有模型 %s 对该提示词的响应", m.name)
+		}
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: response}}}, nil
+	}
+
+	resp, err := m.inner.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) > 0 {
+		m.recording.record(key, resp.Choices[0].Content)
+	}
+	return resp, nil
+}
+
+func (m *recordedModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// 包装嵌入模型：inner 不为空时调用 inner 并录制向量，为空时从录制中重放
+func (r *Recording) Embedder(name string, inner embeddings.EmbedderClient) embeddings.EmbedderClient {
+	return embeddings.EmbedderClientFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+		vectors := make([][]float32, len(texts))
+		if inner == nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			for i, text := range texts {
+				v, ok := r.Embeddings[recordingKey(name, text)]
+				if !ok {
+					r.misses++
+					return nil, fmt.Errorf("录制中没有嵌入模型 %s 对该文本的向量", name)
+				}
+				vectors[i] = v
+			}
+			return vectors, nil
+		}
+
+		vectors, err := inner.CreateEmbedding(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, v := range vectors {
+			if i < len(texts) {
+				r.Embeddings[recordingKey(name, texts[i])] = v
+			}
+		}
+		return vectors, nil
+	})
+}
@@ -0,0 +1,185 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// 一条规则或全部规则的评测指标
+type Score struct {
+	Rule      string  `json:"rule"`
+	TP        int     `json:"tp"`
+	FP        int     `json:"fp"`
+	FN        int     `json:"fn"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+func (s *Score) compute() {
+	s.Precision, s.Recall, s.F1 = 0, 0, 0
+	if s.TP+s.FP > 0 {
+		s.Precision = float64(s.TP) / float64(s.TP+s.FP)
+	}
+	if s.TP+s.FN > 0 {
+		s.Recall = float64(s.TP) / float64(s.TP+s.FN)
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+}
+
+// 误报或漏报
+type Mismatch struct {
+	Location
+	Kind string `json:"kind"` // fp 误报，fn 漏报
+}
+
+// 一次评测的结果
+type Result struct {
+	Time       time.Time  `json:"time"`
+	Corpus     string     `json:"corpus"`
+	Models     []string   `json:"models"`
+	Prompts    []string   `json:"prompts"`
+	Files      int        `json:"files"`
+	Rules      []Score    `json:"rules"`
+	Total      Score      `json:"total"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// 对比预期问题与分析结果，按行号和规则匹配
+func score(expected, found []Location) ([]Score, Score, []Mismatch) {
+	expectedSet := make(map[Location]bool, len(expected))
+	for _, loc := range expected {
+		expectedSet[loc] = true
+	}
+	foundSet := make(map[Location]bool, len(found))
+	for _, loc := range found {
+		foundSet[loc] = true
+	}
+
+	byRule := make(map[string]*Score)
+	ruleScore := func(rule string) *Score {
+		if byRule[rule] == nil {
+			byRule[rule] = &Score{Rule: rule}
+		}
+		return byRule[rule]
+	}
+	var mismatches []Mismatch
+	for loc := range foundSet {
+		if expectedSet[loc] {
+			ruleScore(loc.Rule).TP++
+		} else {
+			ruleScore(loc.Rule).FP++
+			mismatches = append(mismatches, Mismatch{Location: loc, Kind: "fp"})
+		}
+	}
+	for loc := range expectedSet {
+		if !foundSet[loc] {
+			ruleScore(loc.Rule).FN++
+			mismatches = append(mismatches, Mismatch{Location: loc, Kind: "fn"})
+		}
+	}
+
+	total := Score{Rule: "合计"}
+	var rules []Score
+	for _, s := range byRule {
+		s.compute()
+		total.TP += s.TP
+		total.FP += s.FP
+		total.FN += s.FN
+		rules = append(rules, *s)
+	}
+	total.compute()
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Rule < rules[j].Rule })
+	sort.Slice(mismatches, func(i, j int) bool {
+		a, b := mismatches[i], mismatches[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+	return rules, total, mismatches
+}
+
+// 输出评测结果，previous 不为空时附带与上次评测的差异
+func printResult(w io.Writer, result, previous *Result, verbose bool) {
+	fmt.Fprintf(w, "语料: %s  文件数: %d  模型: %v  提示词: %v\n\n", result.Corpus, result.Files, result.Models, result.Prompts)
+
+	prevScores := make(map[string]Score)
+	if previous != nil {
+		for _, s := range previous.Rules {
+			prevScores[s.Rule] = s
+		}
+		prevScores[previous.Total.Rule] = previous.Total
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "规则\tTP\tFP\tFN\t精确率\t召回率\tF1\tΔ精确率\tΔ召回率\tΔF1\t")
+	for _, s := range append(result.Rules, result.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\t", s.Rule, s.TP, s.FP, s.FN, s.Precision, s.Recall, s.F1)
+		if prev, ok := prevScores[s.Rule]; ok {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", delta(s.Precision-prev.Precision), delta(s.Recall-prev.Recall), delta(s.F1-prev.F1))
+		} else {
+			fmt.Fprint(tw, "-\t-\t-\t\n")
+		}
+	}
+	tw.Flush()
+
+	if previous != nil {
+		fmt.Fprintf(w, "\n与 %s 的评测对比：\n", previous.Time.Format("2006-01-02 15:04:05"))
+		printMismatchDiff(w, previous.Mismatches, result.Mismatches)
+	}
+	if verbose {
+		fmt.Fprintln(w, "\n误报与漏报：")
+		for _, m := range result.Mismatches {
+			fmt.Fprintf(w, "  %s %s:%d %s\n", mismatchLabel(m.Kind), m.File, m.Line, m.Rule)
+		}
+	}
+}
+
+// 输出新出现和已消除的误报、漏报
+func printMismatchDiff(w io.Writer, previous, current []Mismatch) {
+	prevSet := make(map[Mismatch]bool, len(previous))
+	for _, m := range previous {
+		prevSet[m] = true
+	}
+	currSet := make(map[Mismatch]bool, len(current))
+	changed := false
+	for _, m := range current {
+		currSet[m] = true
+		if !prevSet[m] {
+			fmt.Fprintf(w, "  + 新增%s %s:%d %s\n", mismatchLabel(m.Kind), m.File, m.Line, m.Rule)
+			changed = true
+		}
+	}
+	for _, m := range previous {
+		if !currSet[m] {
+			fmt.Fprintf(w, "  - 消除%s %s:%d %s\n", mismatchLabel(m.Kind), m.File, m.Line, m.Rule)
+			changed = true
+		}
+	}
+	if !changed {
+		fmt.Fprintln(w, "  误报与漏报没有变化")
+	}
+}
+
+func mismatchLabel(kind string) string {
+	if kind == "fp" {
+		return "误报"
+	}
+	return "漏报"
+}
+
+func delta(d float64) string {
+	if d > -0.0005 && d < 0.0005 {
+		return "0"
+	}
+	return fmt.Sprintf("%+.3f", d)
+}
@@ -1,27 +1,67 @@
 package config
 
 import (
-	"standardizer/global"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-func initDB() {
-	// 初始化数据库连接
-	dsn := AppConfig.Database.Dsn
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// 支持的数据库驱动
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite" // 纯 Go 实现，本地运行无需数据库服务
+)
+
+const defaultSQLiteDSN = "./data/standardizer.db"
+
+// 按配置连接数据库
+func OpenDB() (*gorm.DB, error) {
+	dialector, err := newDialector(AppConfig.Database.Driver, AppConfig.Database.Dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		panic("failed to connect database")
+		return nil, err
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	sqlDB.SetMaxIdleConns(AppConfig.Database.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(AppConfig.Database.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Hour)
-	global.Db = db
+	return db, nil
+}
+
+// 未指定驱动时使用 MySQL
+func newDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverMySQL, "":
+		if dsn == "" {
+			return nil, fmt.Errorf("未配置 MySQL 连接串，可通过环境变量 DATABASE_DSN 设置")
+		}
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		// 数据库文件所在目录不存在时创建
+		path := strings.TrimPrefix(strings.SplitN(dsn, "?", 2)[0], "file:")
+		if path != "" && path != ":memory:" {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, err
+			}
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", driver)
+	}
 }
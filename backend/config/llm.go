@@ -1,35 +1,204 @@
 package config
 
 import (
-	"standardizer/global"
+	"context"
 	"standardizer/models"
 
+	"github.com/go-redis/redis"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
 )
 
-// 规则定义（可逐步扩展）
-var gjbRules = []string{
-	"规则1: 数组索引必须使用无符号类型（如size_t）",
-	"规则2: 禁止使用C风格强制类型转换，必须使用static_cast等C++风格转换",
-	"规则3: 所有指针必须初始化（包括nullptr初始化）",
+// 规则定义（可逐步扩展），示例会按与代码块的相关度选入提示词
+var gjbRules = []models.Rule{
+	{
+		ID: "规则1", Description: "数组索引必须使用无符号类型（如size_t）", Severity: models.SeverityMedium, Languages: []string{"c", "cpp"},
+		Category: "数组", Triggers: []string{`\[`},
+		Examples: []models.RuleExample{
+			{
+				NonCompliant: "for (int i = 0; i < n; i++) {\n    sum += values[i];\n}",
+				Compliant:    "for (size_t i = 0; i < n; i++) {\n    sum += values[i];\n}",
+			},
+			{
+				NonCompliant: "int index = find(key);\nreturn table[index];",
+				Compliant:    "size_t index = find(key);\nreturn table[index];",
+			},
+		},
+	},
+	{
+		ID: "规则2", Description: "禁止使用C风格强制类型转换，必须使用static_cast等C++风格转换", Severity: models.SeverityHigh, Languages: []string{"cpp"},
+		Category: "类型转换", Triggers: []string{`\(\s*(?:const\s+|unsigned\s+|signed\s+)*[A-Za-z_][\w:<>]*\s*\**\s*\)\s*[\w(&*]`},
+		Examples: []models.RuleExample{
+			{
+				NonCompliant: "double ratio = (double)count / total;",
+				Compliant:    "double ratio = static_cast<double>(count) / total;",
+			},
+			{
+				NonCompliant: "Derived *d = (Derived *)base;",
+				Compliant:    "Derived *d = dynamic_cast<Derived *>(base);",
+			},
+		},
+	},
+	{
+		ID: "规则3", Description: "所有指针必须初始化（包括nullptr初始化）", Severity: models.SeverityHigh, Languages: []string{"c", "cpp"},
+		Category: "指针", Triggers: []string{`\*`},
+		Examples: []models.RuleExample{
+			{
+				Language:     "cpp",
+				NonCompliant: "Node *head;\nif (ready) head = create();",
+				Compliant:    "Node *head = nullptr;\nif (ready) head = create();",
+			},
+			{
+				Language:     "c",
+				NonCompliant: "char *buffer;",
+				Compliant:    "char *buffer = NULL;",
+			},
+		},
+	},
+	{
+		ID: "规则4", Description: "禁止使用裸except子句，必须指定捕获的异常类型", Severity: models.SeverityMedium, Languages: []string{"python"},
+		Category: "异常处理", Triggers: []string{`\bexcept\b`},
+		Examples: []models.RuleExample{
+			{
+				NonCompliant: "try:\n    value = int(text)\nexcept:\n    value = 0",
+				Compliant:    "try:\n    value = int(text)\nexcept ValueError:\n    value = 0",
+			},
+		},
+	},
+	{
+		ID: "规则5", Description: "禁止使用列表、字典等可变对象作为函数参数默认值", Severity: models.SeverityHigh, Languages: []string{"python"},
+		Category: "函数", Triggers: []string{`\bdef\b`},
+		Examples: []models.RuleExample{
+			{
+				NonCompliant: "def add(item, items=[]):\n    items.append(item)\n    return items",
+				Compliant:    "def add(item, items=None):\n    if items is None:\n        items = []\n    items.append(item)\n    return items",
+			},
+		},
+	},
 	// 后续添加更多规则...
 }
 
-func InitLLM() {
+// 可在项目配置 rule_set 中选择的规则集
+var ruleSets = map[string][]models.Rule{
+	"gjb": gjbRules,
+}
+
+const (
+	defaultModel = "deepseek-r1:7b"
+	ollamaURL    = "http://localhost:11434"
+)
+
+// 按配置创建 LLM 服务提供方及其限流，未配置时使用本机的 Ollama。
+// redisClient 不为空且启用 redisLimit 时限流在多个进程间共享
+func NewProviderPool(redisClient *redis.Client) *models.ProviderPool {
+	var providers []*models.Provider
+	for _, p := range AppConfig.LLM.Providers {
+		provider := &models.Provider{Name: p.Name, URL: p.URL}
+		if p.Rate > 0 || p.MaxInFlight > 0 {
+			if AppConfig.LLM.RedisLimit && redisClient != nil {
+				provider.Limiter = &models.RedisLimiter{
+					Client:      redisClient,
+					Name:        p.Name,
+					Rate:        p.Rate,
+					Burst:       p.Burst,
+					MaxInFlight: p.MaxInFlight,
+				}
+			} else {
+				provider.Limiter = models.NewLocalLimiter(p.Rate, p.Burst, p.MaxInFlight)
+			}
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		providers = append(providers, &models.Provider{Name: "primary", URL: ollamaURL})
+	}
+	return models.NewProviderPool(providers, AppConfig.LLM.HealthInterval, AppConfig.LLM.FailureThreshold)
+}
+
+// 在各提供方上创建 Ollama 模型，调用失败时切换提供方
+func newModel(pool *models.ProviderPool, name string) (*models.FailoverModel, error) {
+	return pool.Model(func(provider *models.Provider) (llms.Model, error) {
+		return ollama.New(ollama.WithModel(name), ollama.WithServerURL(provider.URL))
+	})
+}
+
+// 按配置创建代码分析器
+func NewCodeAnalyzer(ctx context.Context, pool *models.ProviderPool) (*models.CodeAnalyzer, error) {
+	sharding := models.Sharding{
+		Mode:        AppConfig.Analyzer.Sharding.Mode,
+		ShardTokens: AppConfig.Analyzer.Sharding.ShardTokens,
+	}
+	if err := sharding.Validate(); err != nil {
+		return nil, err
+	}
+
 	// 初始化Ollama模型
-	llm, err := ollama.New(
-		ollama.WithModel("deepseek-r1:7b"),
-		ollama.WithServerURL("http://localhost:11434"),
-	)
+	llm, err := newModel(pool, defaultModel)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	// 多模型投票
+	consensusConfig := AppConfig.Analyzer.Consensus
+	consensus := models.Consensus{
+		Samples:   consensusConfig.Samples,
+		Agreement: consensusConfig.Agreement,
+	}
+	for _, name := range consensusConfig.Models {
+		voter := llms.Model(llm)
+		if name != defaultModel {
+			if voter, err = newModel(pool, name); err != nil {
+				return nil, err
+			}
+		}
+		consensus.Voters = append(consensus.Voters, models.Voter{Name: name, Model: voter})
+	}
+
+	// 提示词模板库
+	promptFile := AppConfig.Analyzer.PromptFile
+	if promptFile == "" {
+		promptFile = "./config/prompts.yaml"
 	}
+	prompts, err := models.LoadPromptLibrary(promptFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// 规则检索
+	var retriever *models.RuleRetriever
+	if retrieval := AppConfig.Analyzer.Retrieval; retrieval.Model != "" {
+		embedder, err := newModel(pool, retrieval.Model)
+		if err != nil {
+			return nil, err
+		}
+		indexFile := retrieval.IndexFile
+		if indexFile == "" {
+			indexFile = "./data/rule_vectors.json"
+		}
+		if retriever, err = models.NewRuleRetriever(embedder, retrieval.Model, indexFile, retrieval.TopK); err != nil {
+			return nil, err
+		}
+	}
+
 	analyzer := &models.CodeAnalyzer{
-		Llm:     llm,
-		Rules:   gjbRules,
-		Results: make(map[string][]models.Issue),
-		Ctx:     global.Ctx,
+		Llm:               llm,
+		Model:             defaultModel,
+		MaxRetries:        AppConfig.Analyzer.Retries,
+		Rules:             gjbRules,
+		RuleSets:          ruleSets,
+		DefaultRuleSet:    "gjb",
+		Results:           make(map[string][]models.Issue),
+		Suppressions:      make(map[string][]models.Suppression),
+		AutoFix:           AppConfig.Analyzer.GenerateFixes,
+		VerifyFixes:       AppConfig.Analyzer.VerifyFixes,
+		Consensus:         consensus,
+		Prompts:           prompts,
+		ExampleTokens:     AppConfig.Analyzer.ExampleTokens,
+		ContextTokens:     AppConfig.Analyzer.ContextTokens,
+		Retriever:         retriever,
+		MaxResponseTokens: AppConfig.Analyzer.MaxTokens,
+		Sharding:          sharding,
+		Ctx:               ctx,
 	}
-	global.LLM = llm
-	global.CodeAnalyzer = analyzer
+	return analyzer, nil
 }
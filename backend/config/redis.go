@@ -1,23 +1,27 @@
 package config
 
 import (
-	"log"
-	"standardizer/global"
-
 	"github.com/go-redis/redis"
 )
 
-func InitRedis() {
-	RedisClient := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // Redis server address
-		Password: "",               // No password by default
-		DB:       0,                // Default DB
-	})
+// 未配置时连接本机的 Redis
+const defaultRedisAddr = "localhost:6379"
 
-	_, err := RedisClient.Ping().Result()
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+// 按配置连接 Redis
+func NewRedis() (*redis.Client, error) {
+	addr := AppConfig.Redis.Addr
+	if addr == "" {
+		addr = defaultRedisAddr
 	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: AppConfig.Redis.Password,
+		DB:       AppConfig.Redis.DB,
+	})
 
-	global.RedisDB = RedisClient
+	if _, err := client.Ping().Result(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
 }
@@ -1,57 +1,63 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
-	"os"
-	"standardizer/controllers"
-	"standardizer/global"
 	"standardizer/models"
+	"standardizer/queue"
+	"standardizer/repository"
 	"standardizer/utils"
 	"time"
 )
 
-// StartConsumer 启动 RabbitMQ 消费者协程
-func StartConsumer() {
-	go func() {
-		for {
-			// 创建 RabbitMQ 通道
-			ch, err := global.RabbitMQConn.Channel()
-			if err != nil {
-				slog.Error("打开 RabbitMQ 通道失败", "error", err)
-				time.Sleep(5 * time.Second) // 等待 5 秒后重试
-				continue
-			}
+// 扫描任务消费者，依赖由应用容器注入，测试时可替换为假实现
+type Worker struct {
+	Ctx         context.Context
+	Jobs        repository.JobRepository
+	Reports     repository.ReportRepository
+	Findings    repository.FindingRepository
+	Calls       repository.LLMCallRepository
+	Queue       queue.Consumer
+	NewAnalyzer AnalyzerFactory
+	Providers   Providers
+	Progress    *models.ProgressHub
+}
 
-			// 声明队列
-			q, err := ch.QueueDeclare(
-				"file_scan_queue", // 队列名称
-				true,              // 持久化
-				false,             // 自动删除
-				false,             // 排他
-				false,             // 等待服务器响应
-				nil,               // 额外参数
-			)
-			if err != nil {
-				slog.Error("声明 RabbitMQ 队列失败", "error", err)
-				ch.Close()
-				time.Sleep(5 * time.Second) // 等待 5 秒后重试
-				continue
+// 扫描一个任务的代码分析器，由 models.CodeAnalyzer 实现
+type Analyzer interface {
+	ProcessFileOrDirectory(path string) error
+	GenerateReport(path string) *models.Report
+}
+
+// 为每个扫描任务创建新的分析器，分析器通过 onFinding 推送流式发现的问题，
+// 通过 onCall 回报每次 LLM 调用。任务指定的提示词模板版本无效时返回错误
+type AnalyzerFactory func(job *models.ScanJob, onFinding func(models.Issue), onCall func(*models.LLMCall)) (Analyzer, error)
+
+// LLM 服务的可用状态，由 models.ProviderPool 实现
+type Providers interface {
+	Available() bool
+	WaitAvailable(ctx context.Context) error
+}
+
+// Start 启动 RabbitMQ 消费者协程，Ctx 结束时停止
+func (w *Worker) Start() {
+	go func() {
+		for w.Ctx.Err() == nil {
+			// LLM 服务全部熔断时暂停消费，消息留在队列中
+			if !w.Providers.Available() {
+				slog.Warn("LLM服务不可用，暂停消费扫描任务")
+				if err := w.Providers.WaitAvailable(w.Ctx); err != nil {
+					return
+				}
+				slog.Info("LLM服务已恢复，继续消费扫描任务")
 			}
 
-			// 注册消费者
-			msgs, err := ch.Consume(
-				q.Name, // 队列名称
-				"",     // 消费者名称
-				true,   // 自动确认
-				false,  // 排他
-				false,  // 本地
-				false,  // 等待服务器响应
-				nil,    // 额外参数
-			)
+			// 注册消费者，每次只取一条消息，处理完成后再确认
+			msgs, closeFn, err := w.Queue.Consume()
 			if err != nil {
 				slog.Error("注册 RabbitMQ 消费者失败", "error", err)
-				ch.Close()
 				time.Sleep(5 * time.Second) // 等待 5 秒后重试
 				continue
 			}
@@ -59,66 +65,163 @@ func StartConsumer() {
 			slog.Info(" [*] 等待文件扫描任务消息。")
 			// 处理接收到的消息
 			for d := range msgs {
-				// 执行文件扫描逻辑
-				filePath := string(d.Body)
-				err := controllers.ProcessFileOrDirectory(filePath, global.CodeAnalyzer)
-				if err != nil {
-					slog.Error("处理文件或目录失败", "error", err)
-					continue
+				if w.HandleScanMessage(d.Body) {
+					d.Ack(false)
+				} else {
+					d.Nack(false, true)
+				}
+				if !w.Providers.Available() || w.Ctx.Err() != nil {
+					break
 				}
-
-				//生成报告
-				report := global.CodeAnalyzer.GenerateReport(filePath)
-
-				// 保存报告到数据库
-				SaveReportInDB(filePath, report)
 			}
 
-			// 如果消息通道关闭，尝试重新连接
-			ch.Close()
+			// 如果消息通道关闭或需要暂停消费，关闭通道后重新连接
+			closeFn()
 			time.Sleep(5 * time.Second)
 		}
 	}()
 }
-func SaveReportInDB(filePath string, report map[string]interface{}) {
-	// filePath := c.PostForm("filePath")
-	// 假设这里有生成报告内容的逻辑
-	// 	// reportContent := generateReport(filePath)
 
-	// 读取文件，计算文件内容的 MD5 低 32 位
-	file, err := os.Open(filePath)
+// 处理一条扫描任务消息，返回 false 表示 LLM 服务中断，消息需重新入队
+func (w *Worker) HandleScanMessage(body []byte) bool {
+	job, err := w.loadScanJob(body)
 	if err != nil {
-		slog.Error("打开文件失败", "error", err)
-		return
+		slog.Error("读取扫描任务失败", "error", err)
+		return true
 	}
-	defer file.Close()
-
-	// 计算文件 MD5
-	md5Low32 := utils.CalcMd5(filePath)
+	w.updateJob(job, map[string]interface{}{"status": models.JobRunning})
 
-	// 转换报告内容为 JSON 字符串
-	reportJSON, err := json.Marshal(report)
+	// 记录每次LLM调用的用量，并累计任务指标。重试后仍失败的分析调用意味着有分片没有结果
+	var calls, failed int
+	var waitMs int64
+	onCall := func(call *models.LLMCall) {
+		call.JobID = job.ID
+		if err := w.Calls.Create(call); err != nil {
+			slog.Error("保存LLM调用记录失败", "job_id", job.ID, "error", err)
+		}
+		calls++
+		waitMs += call.WaitMs
+		if call.Purpose == models.CallAnalyze && call.Outcome == models.CallFailed {
+			failed++
+		}
+	}
+	// 每个任务使用新的分析器，流式分析出的问题立即推送到任务进度
+	analyzer, err := w.NewAnalyzer(job, func(issue models.Issue) {
+		w.Progress.PublishIssue(job.ID, issue)
+	}, onCall)
 	if err != nil {
-		slog.Error("报告内容转换为 JSON 失败", "error", err)
-		// fmt.Printf("报告内容转换为 JSON 失败: %v\n", err)
-		return
+		slog.Error("创建代码分析器失败", "job_id", job.ID, "error", err)
+		w.updateJob(job, map[string]interface{}{"status": models.JobFailed, "error": err.Error()})
+		return true
+	}
+	metrics := func(fields map[string]interface{}) map[string]interface{} {
+		fields["llm_calls"], fields["queue_wait_ms"] = calls, waitMs
+		return fields
 	}
 
-	// 创建 Report 实例
-	reportModel := models.Report{
-		MD5Low32:  md5Low32,
-		Content:   string(reportJSON),
-		CreatedAt: time.Now(),
+	// 执行文件扫描逻辑
+	if err := analyzer.ProcessFileOrDirectory(job.FilePath); err != nil {
+		slog.Error("处理文件或目录失败", "job_id", job.ID, "error", err)
+		w.updateJob(job, metrics(map[string]interface{}{"status": models.JobFailed, "error": err.Error()}))
+		return true
 	}
 
+	// 扫描过程中 LLM 服务全部中断时结果不完整，任务重新排队
+	if !w.Providers.Available() {
+		slog.Warn("扫描期间LLM服务中断，任务重新排队", "job_id", job.ID)
+		w.updateJob(job, metrics(map[string]interface{}{"status": models.JobQueued}))
+		return false
+	}
+
+	// 有分片分析失败时结果不完整，不能保存为已完成
+	if failed > 0 {
+		slog.Error("扫描期间LLM分析调用失败，结果不完整", "job_id", job.ID, "failed", failed)
+		w.updateJob(job, metrics(map[string]interface{}{"status": models.JobFailed, "error": fmt.Sprintf("%d 次LLM分析调用失败，结果不完整", failed)}))
+		return true
+	}
+
+	//生成报告
+	reportModel := analyzer.GenerateReport(job.FilePath)
+
 	// 保存报告到数据库
-	if err := global.Db.AutoMigrate(&models.Report{}); err != nil {
-		slog.Error("自动迁移数据库失败", "error", err)
-		// ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if err := w.SaveReportInDB(job, reportModel); err != nil {
+		w.updateJob(job, metrics(map[string]interface{}{"status": models.JobFailed, "error": err.Error()}))
+		return true
+	}
+
+	w.updateJob(job, metrics(map[string]interface{}{"status": models.JobDone, "report_id": reportModel.ID}))
+	return true
+}
+
+// 解析消息并读取对应的扫描任务，兼容只包含文件路径的旧消息
+func (w *Worker) loadScanJob(body []byte) (*models.ScanJob, error) {
+	var msg models.ScanMessage
+	if err := json.Unmarshal(body, &msg); err != nil || msg.JobID == 0 {
+		job := &models.ScanJob{
+			FilePath: string(body),
+			MD5Low32: utils.CalcMd5(string(body)),
+			Status:   models.JobQueued,
+		}
+		if err := w.Jobs.Create(job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+	return w.Jobs.Get(msg.JobID)
+}
+
+// 更新扫描任务状态并推送到任务进度，任务结束时关闭进度
+func (w *Worker) updateJob(job *models.ScanJob, fields map[string]interface{}) {
+	if err := w.Jobs.Update(job, fields); err != nil {
+		slog.Error("更新扫描任务失败", "job_id", job.ID, "error", err)
+	}
+	status, _ := fields["status"].(string)
+	message, _ := fields["error"].(string)
+	w.Progress.Publish(models.ProgressEvent{Type: models.ProgressStatus, JobID: job.ID, Status: status, Message: message})
+	if status == models.JobDone || status == models.JobFailed {
+		w.Progress.Close(job.ID)
+	}
+}
+
+// 保存报告及其检查发现到数据库
+func (w *Worker) SaveReportInDB(job *models.ScanJob, reportModel *models.Report) error {
+	reportModel.JobID = job.ID
+	reportModel.MD5Low32 = job.MD5Low32
+	reportModel.MinSeverity, reportModel.MinConfidence = job.MinSeverity, job.MinConfidence
+	reportModel.Recount()
+
+	if err := w.applyPreviousTriage(reportModel); err != nil {
+		slog.Error("读取历史判定失败", "error", err)
+		return err
 	}
-	if err := global.Db.Create(&reportModel).Error; err != nil {
+	if err := w.Reports.Create(reportModel); err != nil {
 		slog.Error("保存报告到数据库失败", "error", err)
-		return
+		return err
+	}
+	return nil
+}
+
+// 按指纹将以往扫描中的人工判定延续到本次报告
+func (w *Worker) applyPreviousTriage(reportModel *models.Report) error {
+	var fingerprints []string
+	for _, f := range reportModel.Findings {
+		if f.Fingerprint != "" {
+			fingerprints = append(fingerprints, f.Fingerprint)
+		}
+	}
+	if len(fingerprints) == 0 {
+		return nil
+	}
+
+	latest, err := w.Findings.LatestTriages(fingerprints)
+	if err != nil {
+		return err
+	}
+	for i := range reportModel.Findings {
+		if t, ok := latest[reportModel.Findings[i].Fingerprint]; ok {
+			reportModel.Findings[i].ApplyTriage(t)
+		}
 	}
+	reportModel.Recount()
+	return nil
 }
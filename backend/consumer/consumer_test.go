@@ -0,0 +1,225 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"standardizer/models"
+	"standardizer/repository"
+	"testing"
+)
+
+type fakeJobs struct {
+	repository.JobRepository
+	jobs map[uint]*models.ScanJob
+}
+
+func (r *fakeJobs) Get(id uint) (*models.ScanJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return job, nil
+}
+
+func (r *fakeJobs) Update(job *models.ScanJob, fields map[string]interface{}) error {
+	if status, ok := fields["status"].(string); ok {
+		job.Status = status
+	}
+	if message, ok := fields["error"].(string); ok {
+		job.Error = message
+	}
+	return nil
+}
+
+type fakeReports struct {
+	repository.ReportRepository
+	saved []*models.Report
+}
+
+func (r *fakeReports) Create(report *models.Report) error {
+	report.ID = uint(len(r.saved) + 1)
+	r.saved = append(r.saved, report)
+	return nil
+}
+
+type fakeFindings struct {
+	repository.FindingRepository
+	triages map[string]*models.FindingTriage
+}
+
+func (r *fakeFindings) LatestTriages(fingerprints []string) (map[string]*models.FindingTriage, error) {
+	return r.triages, nil
+}
+
+type fakeCalls struct {
+	repository.LLMCallRepository
+	calls []*models.LLMCall
+}
+
+func (r *fakeCalls) Create(call *models.LLMCall) error {
+	r.calls = append(r.calls, call)
+	return nil
+}
+
+type fakeProviders struct {
+	available bool
+}
+
+func (p *fakeProviders) Available() bool                         { return p.available }
+func (p *fakeProviders) WaitAvailable(ctx context.Context) error { return nil }
+
+// 按预设结果回报 LLM 调用并返回固定报告的分析器
+type fakeAnalyzer struct {
+	onFinding func(models.Issue)
+	onCall    func(*models.LLMCall)
+	outcomes  []string
+	report    *models.Report
+}
+
+func (a *fakeAnalyzer) ProcessFileOrDirectory(path string) error {
+	for _, outcome := range a.outcomes {
+		a.onCall(&models.LLMCall{Purpose: models.CallAnalyze, Outcome: outcome, WaitMs: 10})
+	}
+	a.onFinding(models.Issue{File: path, Line: 1, Rule: "规则1"})
+	return nil
+}
+
+func (a *fakeAnalyzer) GenerateReport(path string) *models.Report {
+	return a.report
+}
+
+type workerFixture struct {
+	worker    *Worker
+	job       *models.ScanJob
+	reports   *fakeReports
+	calls     *fakeCalls
+	providers *fakeProviders
+	analyzers int
+	// 创建分析器返回的错误
+	analyzerErr error
+}
+
+func newWorkerFixture(t *testing.T, outcomes ...string) *workerFixture {
+	t.Helper()
+	f := &workerFixture{
+		job:       &models.ScanJob{FilePath: "a.cpp", MD5Low32: "abc", Status: models.JobQueued},
+		reports:   &fakeReports{},
+		calls:     &fakeCalls{},
+		providers: &fakeProviders{available: true},
+	}
+	f.job.ID = 7
+	triaged := &models.FindingTriage{Fingerprint: "fp1", Status: models.FindingFalsePositive, UserName: "alice"}
+	f.worker = &Worker{
+		Ctx:      context.Background(),
+		Jobs:     &fakeJobs{jobs: map[uint]*models.ScanJob{f.job.ID: f.job}},
+		Reports:  f.reports,
+		Findings: &fakeFindings{triages: map[string]*models.FindingTriage{"fp1": triaged}},
+		Calls:    f.calls,
+		NewAnalyzer: func(job *models.ScanJob, onFinding func(models.Issue), onCall func(*models.LLMCall)) (Analyzer, error) {
+			if f.analyzerErr != nil {
+				return nil, f.analyzerErr
+			}
+			f.analyzers++
+			return &fakeAnalyzer{
+				onFinding: onFinding,
+				onCall:    onCall,
+				outcomes:  outcomes,
+				report: &models.Report{
+					Files: []models.FileResult{{File: "a.cpp"}},
+					Findings: []models.Finding{
+						{File: "a.cpp", Line: 1, Rule: "规则1", Severity: models.SeverityHigh, Status: models.FindingOpen, Fingerprint: "fp1"},
+						{File: "a.cpp", Line: 2, Rule: "规则3", Severity: models.SeverityHigh, Status: models.FindingOpen, Fingerprint: "fp2"},
+					},
+				},
+			}, nil
+		},
+		Providers: f.providers,
+		Progress:  models.NewProgressHub(),
+	}
+	return f
+}
+
+func (f *workerFixture) handle(t *testing.T) bool {
+	t.Helper()
+	body, err := json.Marshal(models.ScanMessage{JobID: f.job.ID, FilePath: f.job.FilePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.worker.HandleScanMessage(body)
+}
+
+func TestHandleScanMessageSavesReport(t *testing.T) {
+	f := newWorkerFixture(t, models.CallOK, models.CallOK)
+	if !f.handle(t) {
+		t.Fatal("message requeued, want ack")
+	}
+	if f.job.Status != models.JobDone {
+		t.Fatalf("job status %q, want %q", f.job.Status, models.JobDone)
+	}
+	if len(f.reports.saved) != 1 {
+		t.Fatalf("saved %d reports, want 1", len(f.reports.saved))
+	}
+	saved := f.reports.saved[0]
+	if saved.JobID != f.job.ID || saved.MD5Low32 != f.job.MD5Low32 {
+		t.Errorf("report job_id=%d md5=%q, want %d %q", saved.JobID, saved.MD5Low32, f.job.ID, f.job.MD5Low32)
+	}
+	// 以往的误报判定延续到本次报告
+	if saved.Findings[0].Status != models.FindingFalsePositive || saved.TotalIssues != 1 || saved.Suppressed != 1 {
+		t.Errorf("got status %q total=%d suppressed=%d, want previous triage applied", saved.Findings[0].Status, saved.TotalIssues, saved.Suppressed)
+	}
+	if len(f.calls.calls) != 2 || f.calls.calls[0].JobID != f.job.ID {
+		t.Errorf("recorded %d calls, want 2 with job_id %d", len(f.calls.calls), f.job.ID)
+	}
+}
+
+func TestHandleScanMessageNewAnalyzerPerJob(t *testing.T) {
+	f := newWorkerFixture(t, models.CallOK)
+	f.handle(t)
+	f.job.Status = models.JobQueued
+	f.handle(t)
+	if f.analyzers != 2 {
+		t.Errorf("created %d analyzers, want one per job", f.analyzers)
+	}
+}
+
+func TestHandleScanMessageFailsOnFailedShard(t *testing.T) {
+	f := newWorkerFixture(t, models.CallOK, models.CallFailed)
+	if !f.handle(t) {
+		t.Fatal("message requeued, want ack")
+	}
+	if f.job.Status != models.JobFailed || f.job.Error == "" {
+		t.Errorf("job status %q error %q, want failed with error", f.job.Status, f.job.Error)
+	}
+	if len(f.reports.saved) != 0 {
+		t.Errorf("saved %d reports for incomplete scan, want 0", len(f.reports.saved))
+	}
+}
+
+func TestHandleScanMessageRequeuesWhenProvidersDown(t *testing.T) {
+	f := newWorkerFixture(t, models.CallFailed)
+	f.providers.available = false
+	if f.handle(t) {
+		t.Fatal("message acked, want requeue")
+	}
+	if f.job.Status != models.JobQueued {
+		t.Errorf("job status %q, want %q", f.job.Status, models.JobQueued)
+	}
+	if len(f.reports.saved) != 0 {
+		t.Errorf("saved %d reports, want 0", len(f.reports.saved))
+	}
+}
+
+func TestHandleScanMessageFailsOnInvalidPrompt(t *testing.T) {
+	f := newWorkerFixture(t, models.CallOK)
+	f.analyzerErr = errors.New("提示词模板 gjb-cpp@v9 不存在")
+	if !f.handle(t) {
+		t.Fatal("message requeued, want ack")
+	}
+	if f.job.Status != models.JobFailed || f.job.Error != f.analyzerErr.Error() {
+		t.Errorf("job status %q error %q, want failed with %q", f.job.Status, f.job.Error, f.analyzerErr)
+	}
+	if len(f.reports.saved) != 0 {
+		t.Errorf("saved %d reports, want 0", len(f.reports.saved))
+	}
+}
@@ -4,47 +4,40 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"standardizer/global"
 	"standardizer/models"
+	"standardizer/repository"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
-	"gorm.io/gorm"
 )
 
 var cacheKey = "articles"
 
-func CreateArticle(ctx *gin.Context) {
+func (h *ArticleHandler) CreateArticle(ctx *gin.Context) {
 	var article models.Article
 	if err := ctx.ShouldBindJSON(&article); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	}
 
-	if err := global.Db.AutoMigrate(&models.Article{}); err != nil {
+	if err := h.Articles.Create(&article); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := global.Db.Create(&article).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := global.RedisDB.Del(cacheKey).Err(); err != nil {
+	if err := h.Cache.Del(cacheKey); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 
 	ctx.JSON(http.StatusOK, article)
 }
 
-func GetArticles(ctx *gin.Context) {
+func (h *ArticleHandler) GetArticles(ctx *gin.Context) {
 
-	cachedData, err := global.RedisDB.Get(cacheKey).Result()
+	cachedData, err := h.Cache.Get(cacheKey)
 
-	if err == redis.Nil {
-		var articles []models.Article
-		if err := global.Db.Find(&articles).Error; err != nil {
+	if err == ErrCacheMiss {
+		articles, err := h.Articles.List()
+		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -54,7 +47,7 @@ func GetArticles(ctx *gin.Context) {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		if err := global.RedisDB.Set(cacheKey, articleJSON, 10*time.Minute).Err(); err != nil {
+		if err := h.Cache.Set(cacheKey, string(articleJSON), 10*time.Minute); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
 		ctx.JSON(http.StatusOK, articles)
@@ -73,11 +66,10 @@ func GetArticles(ctx *gin.Context) {
 
 }
 
-func GetArticleByID(ctx *gin.Context) {
-	var article models.Article
-	id := ctx.Param("id")
-	if err := global.Db.Where("id = ?", id).First(&article).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (h *ArticleHandler) GetArticleByID(ctx *gin.Context) {
+	article, err := h.Articles.Get(paramID(ctx))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		} else {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"net/http"
-	"standardizer/global"
 	"standardizer/models"
 	"standardizer/utils"
 
@@ -12,7 +11,7 @@ import (
 	"path/filepath"
 )
 
-func Register(ctx *gin.Context) {
+func (h *AuthHandler) Register(ctx *gin.Context) {
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -30,12 +29,7 @@ func Register(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 	}
 
-	if err := global.Db.AutoMigrate(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error migrating user"})
-		return
-	}
-
-	if err := global.Db.Create(&user).Error; err != nil {
+	if err := h.Users.Create(&user); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
 		return
 	}
@@ -44,7 +38,7 @@ func Register(ctx *gin.Context) {
 
 }
 
-func Login(ctx *gin.Context) {
+func (h *AuthHandler) Login(ctx *gin.Context) {
 	var input struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
@@ -55,9 +49,8 @@ func Login(ctx *gin.Context) {
 		return
 	}
 
-	var user models.User
-
-	if err := global.Db.Where("name = ?", input.Username).First(&user).Error; err != nil {
+	user, err := h.Users.FindByName(input.Username)
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Credentials"})
 		return
 	}
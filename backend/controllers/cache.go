@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 键不存在
+var ErrCacheMiss = errors.New("缓存不存在")
+
+// 文章缓存和点赞计数使用的键值存储
+type Cache interface {
+	Get(key string) (string, error) // 键不存在时返回 ErrCacheMiss
+	Set(key, value string, ttl time.Duration) error
+	Del(key string) error
+	Incr(key string) error
+}
+
+// 基于 Redis 的缓存
+type RedisCache struct {
+	Client *redis.Client
+}
+
+func (c *RedisCache) Get(key string) (string, error) {
+	value, err := c.Client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) Set(key, value string, ttl time.Duration) error {
+	return c.Client.Set(key, value, ttl).Err()
+}
+
+func (c *RedisCache) Del(key string) error {
+	return c.Client.Del(key).Err()
+}
+
+func (c *RedisCache) Incr(key string) error {
+	return c.Client.Incr(key).Err()
+}
@@ -3,15 +3,14 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"standardizer/global"
 	"standardizer/models"
+	"standardizer/repository"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func CreateExchangeRate(ctx *gin.Context) {
+func (h *ExchangeRateHandler) CreateExchangeRate(ctx *gin.Context) {
 	var exchangeRate models.ExchangeRate
 
 	if err := ctx.BindJSON(&exchangeRate); err != nil {
@@ -21,12 +20,7 @@ func CreateExchangeRate(ctx *gin.Context) {
 
 	exchangeRate.Date = time.Now()
 
-	if err := global.Db.AutoMigrate(&exchangeRate); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := global.Db.Create(&exchangeRate).Error; err != nil {
+	if err := h.Rates.Create(&exchangeRate); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -34,10 +28,10 @@ func CreateExchangeRate(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, exchangeRate)
 }
 
-func GetExchangeRates(ctx *gin.Context) {
-	var exchangeRates []models.ExchangeRate
-	if err := global.Db.Find(&exchangeRates).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (h *ExchangeRateHandler) GetExchangeRates(ctx *gin.Context) {
+	exchangeRates, err := h.Rates.List()
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "No exchange rates found"})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
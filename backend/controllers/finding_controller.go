@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"standardizer/models"
+	"standardizer/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultFindingLimit = 50
+	maxFindingLimit     = 500
+)
+
+// 查询报告的检查发现，支持过滤、排序和游标分页
+//
+//	GET /api/reports/:id/findings?file=src/*.cpp&rule=规则1,规则2&severity=high&status=open&engine=static&min_severity=medium&min_confidence=0.5&q=cast&sort=severity&order=asc&limit=50&cursor=...
+func (h *ReportHandler) GetFindings(ctx *gin.Context) {
+	reportModel, err := h.Reports.Get(paramID(ctx), false)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "报告不存在"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 无效的严重级别或置信度返回 400，不静默忽略
+	minSeverity, minConfidence, err := parseGate(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := repository.FindingQuery{
+		ReportID:      reportModel.ID,
+		File:          ctx.Query("file"),
+		Rules:         splitQueryList(ctx.Query("rule")),
+		Severities:    splitQueryList(ctx.Query("severity")),
+		Statuses:      splitQueryList(ctx.Query("status")),
+		Engines:       splitQueryList(ctx.Query("engine")),
+		MinSeverity:   minSeverity,
+		MinConfidence: minConfidence,
+		Text:          ctx.Query("q"),
+		Sort:          ctx.DefaultQuery("sort", "file"),
+		Desc:          ctx.Query("order") == "desc",
+		Limit:         defaultFindingLimit,
+	}
+	if !repository.ValidFindingSort(query.Sort) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的排序字段: " + query.Sort})
+		return
+	}
+	if s := ctx.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须为正整数"})
+			return
+		}
+		query.Limit = min(n, maxFindingLimit)
+	}
+	if s := ctx.Query("cursor"); s != "" {
+		cursor, err := decodeFindingCursor(s)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的分页游标"})
+			return
+		}
+		query.After = &cursor
+	}
+
+	// 多取一条判断是否还有下一页
+	limit := query.Limit
+	query.Limit++
+	findings, total, err := h.Findings.Find(query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(findings) > limit {
+		findings = findings[:limit]
+		last := findings[limit-1]
+		nextCursor = encodeFindingCursor(repository.FindingCursor{File: last.File, Line: last.Line, Rank: last.Rank, ID: last.ID})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"findings":    findings,
+		"total":       total,
+		"next_cursor": nextCursor,
+	})
+}
+
+// 逗号分隔的查询参数
+func splitQueryList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func encodeFindingCursor(cursor repository.FindingCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeFindingCursor(s string) (repository.FindingCursor, error) {
+	var cursor repository.FindingCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// 人工判定请求
+type triageInput struct {
+	Status        string `json:"status" binding:"required"`
+	Justification string `json:"justification"`
+}
+
+// 修改检查发现的状态：确认、误报、不修复或重新打开，误报和不修复必须说明理由
+func (h *ReportHandler) TriageFinding(ctx *gin.Context) {
+	var input triageInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Justification = strings.TrimSpace(input.Justification)
+	if !models.IsValidFindingStatus(input.Status) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态: " + input.Status})
+		return
+	}
+	if models.IsSuppressedStatus(input.Status) && input.Justification == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "标记为误报或不修复时必须填写理由"})
+		return
+	}
+
+	finding, err := h.Findings.Triage(paramID(ctx), &models.FindingTriage{
+		Status:        input.Status,
+		Justification: input.Justification,
+		UserName:      ctx.GetString("username"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "检查发现不存在"})
+		case errors.Is(err, repository.ErrSameStatus):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, finding)
+}
+
+// 查询检查发现的判定历史，包括以往扫描中同一指纹的判定
+func (h *ReportHandler) GetFindingTriage(ctx *gin.Context) {
+	finding, err := h.Findings.Get(paramID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "检查发现不存在"})
+		return
+	}
+
+	history, err := h.Findings.TriageHistory(finding)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"finding": finding, "history": history})
+}
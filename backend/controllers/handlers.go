@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"standardizer/models"
+	"standardizer/queue"
+	"standardizer/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 各组接口的处理器，依赖由 main 中构建的应用容器注入，测试时可替换为假实现
+
+// 注册与登录
+type AuthHandler struct {
+	Users repository.UserRepository
+}
+
+// 文章及点赞
+type ArticleHandler struct {
+	Articles repository.ArticleRepository
+	Cache    Cache
+}
+
+// 汇率
+type ExchangeRateHandler struct {
+	Rates repository.ExchangeRateRepository
+}
+
+// 扫描任务、LLM 服务状态与调用用量
+type ScanHandler struct {
+	Jobs      repository.JobRepository
+	Reports   repository.ReportRepository
+	Findings  repository.FindingRepository
+	Calls     repository.LLMCallRepository
+	Queue     queue.Publisher
+	Providers ProviderMonitor
+	Progress  *models.ProgressHub
+	// 提示词模板库，用于校验请求指定的模板版本
+	Prompts *models.PromptLibrary
+}
+
+// LLM 服务提供方的状态，由 models.ProviderPool 实现
+type ProviderMonitor interface {
+	Status() []models.ProviderStatus
+	Available() bool
+}
+
+// 报告、检查发现与提示词模板
+type ReportHandler struct {
+	Jobs     repository.JobRepository
+	Reports  repository.ReportRepository
+	Findings repository.FindingRepository
+	Prompts  *models.PromptLibrary
+}
+
+// 路由使用的全部处理器
+type Handlers struct {
+	Auth         *AuthHandler
+	Article      *ArticleHandler
+	ExchangeRate *ExchangeRateHandler
+	Scan         *ScanHandler
+	Report       *ReportHandler
+}
+
+// 路径参数中的记录 ID，无效时返回 0，按记录不存在处理
+func paramID(ctx *gin.Context) uint {
+	id, _ := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	return uint(id)
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"standardizer/models"
+	"standardizer/repository"
+	"standardizer/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type fakeJobs struct {
+	repository.JobRepository
+	created []*models.ScanJob
+	byID    map[uint]*models.ScanJob
+}
+
+func (r *fakeJobs) Get(id uint) (*models.ScanJob, error) {
+	if job, ok := r.byID[id]; ok {
+		return job, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeJobs) Create(job *models.ScanJob) error {
+	job.ID = uint(len(r.created) + 1)
+	r.created = append(r.created, job)
+	return nil
+}
+
+type fakeReports struct {
+	repository.ReportRepository
+	latest map[string]*models.Report
+	byID   map[uint]*models.Report
+}
+
+func (r *fakeReports) Latest(md5Low32 string, withFindings bool) (*models.Report, error) {
+	if report, ok := r.latest[md5Low32]; ok {
+		return report, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeReports) Get(id uint, withFindings bool) (*models.Report, error) {
+	if report, ok := r.byID[id]; ok {
+		return report, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+type fakeFindings struct {
+	repository.FindingRepository
+	gate      int64
+	query     repository.FindingQuery
+	found     []models.Finding
+	triageErr error
+}
+
+func (r *fakeFindings) CountGate(reportID uint, minSeverity string, minConfidence float64) (int64, error) {
+	return r.gate, nil
+}
+
+func (r *fakeFindings) Find(query repository.FindingQuery) ([]models.Finding, int64, error) {
+	r.query = query
+	return r.found, int64(len(r.found)), nil
+}
+
+func (r *fakeFindings) Triage(id uint, triage *models.FindingTriage) (*models.Finding, error) {
+	if r.triageErr != nil {
+		return nil, r.triageErr
+	}
+	return &models.Finding{ID: id, Status: triage.Status, TriagedBy: triage.UserName}, nil
+}
+
+type fakePublisher struct {
+	published [][]byte
+	err       error
+}
+
+func (p *fakePublisher) Publish(body []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, body)
+	return nil
+}
+
+func serve(t *testing.T, method, path string, handler gin.HandlerFunc, route string, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) { ctx.Set("username", "alice") })
+	router.Handle(method, route, handler)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 在临时目录的 uploads 下创建待扫描文件
+func uploadFile(t *testing.T, name string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("uploads", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("uploads", name), []byte("int *p;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		cached     bool
+		publishErr error
+		wantCode   int
+		wantJobs   int
+	}{
+		{name: "新文件创建任务并发布", wantCode: http.StatusAccepted, wantJobs: 1},
+		{name: "已有报告按门禁返回", query: "?min_severity=high", cached: true, wantCode: http.StatusOK},
+		{name: "无效置信度", query: "?min_confidence=2", wantCode: http.StatusBadRequest},
+		{name: "发布失败", publishErr: errors.New("连接已关闭"), wantCode: http.StatusInternalServerError, wantJobs: 1},
+		{name: "提示词版本不存在", query: "?prompt=gjb-cpp@v9", wantCode: http.StatusBadRequest},
+		{name: "已有报告使用了指定版本", query: "?min_severity=high&prompt=gjb-cpp@v4", cached: true, wantCode: http.StatusOK},
+		{name: "已有报告未使用指定版本时重新扫描", query: "?prompt=gjb-cpp@v3", cached: true, wantCode: http.StatusAccepted, wantJobs: 1},
+	}
+	prompts := &models.PromptLibrary{Templates: []*models.PromptTemplate{
+		{Name: "gjb-cpp", Version: "v3"},
+		{Name: "gjb-cpp", Version: "v4"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploadFile(t, "a.cpp")
+			md5Low32 := utils.CalcMd5(filepath.Join("uploads", "a.cpp"))
+			reports := &fakeReports{latest: map[string]*models.Report{}}
+			if tt.cached {
+				reports.latest[md5Low32] = &models.Report{ID: 3, MD5Low32: md5Low32, SchemaVersion: 1, Prompts: []string{"gjb-cpp@v4"}}
+			}
+			jobs := &fakeJobs{}
+			queue := &fakePublisher{err: tt.publishErr}
+			h := &ScanHandler{Jobs: jobs, Reports: reports, Findings: &fakeFindings{gate: 2}, Queue: queue, Prompts: prompts}
+
+			w := serve(t, http.MethodPost, "/scan"+tt.query, h.GetResponse, "/scan", "", map[string]string{"File-Name": "a.cpp"})
+			if w.Code != tt.wantCode {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+			if len(jobs.created) != tt.wantJobs {
+				t.Fatalf("created %d jobs, want %d", len(jobs.created), tt.wantJobs)
+			}
+
+			switch tt.wantCode {
+			case http.StatusAccepted:
+				job := jobs.created[0]
+				if job.UserName != "alice" || job.Status != models.JobQueued || job.MD5Low32 != md5Low32 {
+					t.Errorf("got job %+v", job)
+				}
+				if want := splitQueryList(strings.TrimPrefix(tt.query, "?prompt=")); len(want) > 0 && (len(job.Prompts) != 1 || job.Prompts[0] != want[0]) {
+					t.Errorf("job prompts %v, want %v", job.Prompts, want)
+				}
+				var msg models.ScanMessage
+				if len(queue.published) != 1 || json.Unmarshal(queue.published[0], &msg) != nil || msg.JobID != job.ID {
+					t.Errorf("published %q, want message for job %d", queue.published, job.ID)
+				}
+			case http.StatusOK:
+				var got models.Report
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.GateIssues != 2 || got.Passed || got.MinSeverity != "high" {
+					t.Errorf("got gate_issues=%d passed=%v min_severity=%q", got.GateIssues, got.Passed, got.MinSeverity)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFindings(t *testing.T) {
+	findings := &fakeFindings{found: []models.Finding{
+		{ID: 1, File: "a.cpp", Line: 1},
+		{ID: 2, File: "a.cpp", Line: 2},
+		{ID: 3, File: "b.cpp", Line: 1},
+	}}
+	h := &ReportHandler{Reports: &fakeReports{byID: map[uint]*models.Report{5: {ID: 5}}}, Findings: findings}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{name: "报告不存在", path: "/reports/9/findings", wantCode: http.StatusNotFound},
+		{name: "无效严重级别", path: "/reports/5/findings?min_severity=urgent", wantCode: http.StatusBadRequest},
+		{name: "无效置信度", path: "/reports/5/findings?min_confidence=abc", wantCode: http.StatusBadRequest},
+		{name: "不支持的排序", path: "/reports/5/findings?sort=author", wantCode: http.StatusBadRequest},
+		{name: "无效游标", path: "/reports/5/findings?cursor=!!", wantCode: http.StatusBadRequest},
+		{name: "分页", path: "/reports/5/findings?limit=2&rule=规则1,规则2&min_confidence=0.5", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, http.MethodGet, tt.path, h.GetFindings, "/reports/:id/findings", "", nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+		})
+	}
+
+	// 多取一条判断下一页，游标指向本页最后一条
+	if findings.query.Limit != 3 || findings.query.MinConfidence != 0.5 || len(findings.query.Rules) != 2 {
+		t.Errorf("got query %+v", findings.query)
+	}
+	w := serve(t, http.MethodGet, "/reports/5/findings?limit=2", h.GetFindings, "/reports/:id/findings", "", nil)
+	var page struct {
+		Findings   []models.Finding `json:"findings"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := decodeFindingCursor(page.NextCursor)
+	if err != nil || len(page.Findings) != 2 || cursor.ID != 2 || cursor.Line != 2 {
+		t.Errorf("got %d findings, cursor %+v (%v)", len(page.Findings), cursor, err)
+	}
+}
+
+func TestTriageFinding(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		triageErr error
+		wantCode  int
+	}{
+		{name: "确认", body: `{"status":"accepted"}`, wantCode: http.StatusOK},
+		{name: "无效状态", body: `{"status":"ignored"}`, wantCode: http.StatusBadRequest},
+		{name: "误报缺少理由", body: `{"status":"false_positive","justification":"  "}`, wantCode: http.StatusBadRequest},
+		{name: "检查发现不存在", body: `{"status":"accepted"}`, triageErr: repository.ErrNotFound, wantCode: http.StatusNotFound},
+		{name: "状态未变化", body: `{"status":"accepted"}`, triageErr: repository.ErrSameStatus, wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ReportHandler{Findings: &fakeFindings{triageErr: tt.triageErr}}
+			w := serve(t, http.MethodPost, "/findings/4/triage", h.TriageFinding, "/findings/:id/triage", tt.body, map[string]string{"Content-Type": "application/json"})
+			if w.Code != tt.wantCode {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && !strings.Contains(w.Body.String(), `"triaged_by":"alice"`) {
+				t.Errorf("got %s, want triage by alice", w.Body)
+			}
+		})
+	}
+}
+
+func TestDownloadReportReflectsTriage(t *testing.T) {
+	job := &models.ScanJob{FilePath: "uploads/a.cpp", Status: models.JobDone, ReportID: 5}
+	job.ID = 2
+	pending := &models.ScanJob{FilePath: "uploads/b.cpp", Status: models.JobRunning}
+	pending.ID = 3
+	// 扫描完成后人工判定了一条误报
+	reportModel := &models.Report{ID: 5, Findings: []models.Finding{
+		{File: "a.cpp", Line: 1, Rule: "规则1", Severity: models.SeverityHigh, Status: models.FindingOpen},
+		{File: "a.cpp", Line: 2, Rule: "规则3", Severity: models.SeverityHigh, Status: models.FindingFalsePositive, Justification: "构造函数中初始化", TriagedBy: "alice"},
+	}}
+	h := &ScanHandler{
+		Jobs:    &fakeJobs{byID: map[uint]*models.ScanJob{2: job, 3: pending}},
+		Reports: &fakeReports{byID: map[uint]*models.Report{5: reportModel}},
+	}
+
+	if w := serve(t, http.MethodGet, "/download-report?job_id=3", h.DownloadReport, "/download-report", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unfinished job: got %d, want 404", w.Code)
+	}
+	w := serve(t, http.MethodGet, "/download-report?job_id=2", h.DownloadReport, "/download-report", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	if name := w.Header().Get("File-Name"); name != "a_result.xlsx" {
+		t.Errorf("file name %q", name)
+	}
+	f, err := excelize.OpenReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	detail, _ := f.GetRows("问题明细")
+	audit, _ := f.GetRows("审计")
+	if len(detail) != 2 || len(audit) != 2 || audit[1][4] != "构造函数中初始化" {
+		t.Errorf("got %d detail rows and audit %v, want the triaged finding moved to the audit sheet", len(detail), audit)
+	}
+}
+
+func TestGetResponseDirectoriesDoNotShareReports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for name, content := range map[string]string{"p1/a.cpp": "int *p;\n", "p2/a.cpp": "int *q;\n"} {
+		path := filepath.Join("uploads", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p1 := utils.CalcMd5(filepath.Join("uploads", "p1"))
+	reports := &fakeReports{latest: map[string]*models.Report{p1: {ID: 3, MD5Low32: p1, SchemaVersion: 1}}}
+	jobs := &fakeJobs{}
+	h := &ScanHandler{Jobs: jobs, Reports: reports, Findings: &fakeFindings{}, Queue: &fakePublisher{}}
+
+	if w := serve(t, http.MethodPost, "/scan", h.GetResponse, "/scan", "", map[string]string{"File-Name": "p1"}); w.Code != http.StatusOK {
+		t.Fatalf("scanned project: got %d %s, want cached report", w.Code, w.Body)
+	}
+	if w := serve(t, http.MethodPost, "/scan", h.GetResponse, "/scan", "", map[string]string{"File-Name": "p2"}); w.Code != http.StatusAccepted {
+		t.Fatalf("other project: got %d %s, want a new scan", w.Code, w.Body)
+	}
+	if len(jobs.created) != 1 || jobs.created[0].MD5Low32 == "" || jobs.created[0].MD5Low32 == p1 {
+		t.Errorf("created jobs %+v, want one job keyed by the second project", jobs.created)
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"standardizer/models"
+	"standardizer/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询扫描任务的状态和指标
+//
+//	GET /api/jobs/:id
+func (h *ScanHandler) GetJob(ctx *gin.Context) {
+	job, err := h.Jobs.Get(paramID(ctx))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "扫描任务不存在"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, job)
+}
+
+// 以 Server-Sent Events 推送扫描任务进度：先补发已发生的事件，任务结束后关闭连接
+//
+//	GET /api/jobs/:id/progress
+func (h *ScanHandler) GetJobProgress(ctx *gin.Context) {
+	job, err := h.Jobs.Get(paramID(ctx))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "扫描任务不存在"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 已结束的任务只返回最终状态
+	if job.Status == models.JobDone || job.Status == models.JobFailed {
+		ctx.SSEvent(models.ProgressStatus, models.ProgressEvent{
+			Type:    models.ProgressStatus,
+			JobID:   job.ID,
+			Status:  job.Status,
+			Message: job.Error,
+			Time:    job.UpdatedAt,
+		})
+		return
+	}
+
+	history, events, cancel := h.Progress.Subscribe(job.ID)
+	defer cancel()
+	for _, event := range history {
+		ctx.SSEvent(event.Type, event)
+	}
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
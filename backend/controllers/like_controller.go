@@ -2,28 +2,26 @@ package controllers
 
 import (
 	"net/http"
-	"standardizer/global"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
 )
 
-func LikeArticle(ctx *gin.Context) {
+func (h *ArticleHandler) LikeArticle(ctx *gin.Context) {
 	articleID := ctx.Param("id")
 
 	likeKey := "article:" + articleID + ":likes"
-	if err := global.RedisDB.Incr(likeKey).Err(); err != nil {
+	if err := h.Cache.Incr(likeKey); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully liked article"})
 }
 
-func GetArticleLikes(ctx *gin.Context) {
+func (h *ArticleHandler) GetArticleLikes(ctx *gin.Context) {
 	articleID := ctx.Param("id")
 
 	likeKey := "article:" + articleID + ":likes"
-	likes, err := global.RedisDB.Get(likeKey).Result()
-	if err == redis.Nil {
+	likes, err := h.Cache.Get(likeKey)
+	if err == ErrCacheMiss {
 		likes = "0"
 	} else if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"standardizer/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按任务、用户、规则集或模型汇总 LLM 调用的 token 用量、限流等待时间和耗时，用于评估模型主机容量
+//
+//	GET /api/llm-calls/stats?group_by=user&job_id=12&user=alice&rule_set=gjb&model=deepseek-r1:7b&since=2026-01-01
+func (h *ScanHandler) GetLLMCallStats(ctx *gin.Context) {
+	groupBy := ctx.DefaultQuery("group_by", "job")
+	if !repository.ValidLLMCallGroup(groupBy) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的分组方式: " + groupBy})
+		return
+	}
+
+	query := repository.LLMCallQuery{
+		GroupBy: groupBy,
+		JobID:   ctx.Query("job_id"),
+		User:    ctx.Query("user"),
+		RuleSet: ctx.Query("rule_set"),
+		Model:   ctx.Query("model"),
+	}
+	if since := ctx.Query("since"); since != "" {
+		t, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期: " + since})
+			return
+		}
+		query.Since = t
+	}
+
+	stats, err := h.Calls.Stats(query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	total := repository.LLMCallStats{GroupKey: "total"}
+	for _, s := range stats {
+		total.Calls += s.Calls
+		total.PromptTokens += s.PromptTokens
+		total.CompletionTokens += s.CompletionTokens
+		total.WaitMs += s.WaitMs
+		total.LatencyMs += s.LatencyMs
+		total.Retries += s.Retries
+		total.Aborted += s.Aborted
+		total.Failed += s.Failed
+	}
+	if total.Calls > 0 {
+		total.AvgWaitMs = float64(total.WaitMs) / float64(total.Calls)
+		total.AvgLatencyMs = float64(total.LatencyMs) / float64(total.Calls)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"group_by": groupBy, "groups": stats, "total": total})
+}
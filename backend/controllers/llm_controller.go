@@ -1,23 +1,25 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
-	"standardizer/global"
+	"slices"
 	"standardizer/utils"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/streadway/amqp"
-	"github.com/xuri/excelize/v2"
 
 	"standardizer/models"
+	"standardizer/report"
 )
 
-func GetResponse(ctx *gin.Context) {
+func (h *ScanHandler) GetResponse(ctx *gin.Context) {
 	// 从请求头获取文件名
 	fileName := ctx.GetHeader("File-Name")
 	if fileName == "" {
@@ -35,160 +37,154 @@ func GetResponse(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "文件不存在"})
 		return
 	}
+	minSeverity, minConfidence, err := parseGate(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// prompt 参数指定本次扫描使用的模板版本，如 prompt=gjb-cpp@v3，用于提示词 A/B 对比
+	prompts := splitQueryList(ctx.Query("prompt"))
+	for _, ref := range prompts {
+		if h.Prompts.Lookup(ref) == nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("提示词模板 %s 不存在", ref)})
+			return
+		}
+	}
+	// 目录按其中的文件计算，读取失败时不能使用空值作为报告的键
 	md5Low32 := utils.CalcMd5(filePath)
-	// 检查文件报告是否已在数据库中，若在，则直接返回报告
-	var reportModel models.Report
-	if err := global.Db.Where("md5_low32 = ?", md5Low32).First(&reportModel).Error; err == nil {
+	if md5Low32 == "" {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取文件"})
+		return
+	}
+	// 检查文件报告是否已在数据库中，若在，则按本次请求的门禁阈值返回报告。
+	// 指定了模板版本时只复用使用了这些版本的报告
+	if reportModel, err := h.Reports.Latest(md5Low32, false); err == nil && usedPrompts(reportModel, prompts) {
 		slog.Info("文件报告已存在于数据库", "file", filePath)
+		if err := h.evaluateGate(reportModel, minSeverity, minConfidence); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusOK, reportModel)
 		return
 	}
 
-	// 若数据库中无报告，将任务发布到消息队列
-	ch, err := global.RabbitMQConn.Channel()
-	if err != nil {
-		slog.Error("打开 RabbitMQ 通道失败", "error", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "无法连接到消息队列"})
+	// 若数据库中无报告，创建扫描任务并发布到消息队列
+	job := models.ScanJob{
+		FilePath:      filePath,
+		MD5Low32:      md5Low32,
+		UserName:      ctx.GetString("username"),
+		Status:        models.JobQueued,
+		MinSeverity:   minSeverity,
+		MinConfidence: minConfidence,
+		Prompts:       prompts,
+	}
+	if err := h.Jobs.Create(&job); err != nil {
+		slog.Error("创建扫描任务失败", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "无法创建扫描任务"})
 		return
 	}
-	defer ch.Close()
-
-	q, err := ch.QueueDeclare(
-		"file_scan_queue", // 队列名称
-		true,              // 持久化
-		false,             // 自动删除
-		false,             // 排他
-		false,             // 等待服务器响应
-		nil,               // 额外参数
-	)
+	body, err := json.Marshal(models.ScanMessage{JobID: job.ID, FilePath: filePath})
 	if err != nil {
-		slog.Error("声明 RabbitMQ 队列失败", "error", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "无法声明消息队列"})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = ch.Publish(
-		"",     // 交换器
-		q.Name, // 路由键
-		false,  // 强制
-		false,  // 立即
-		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "text/plain",
-			Body:         []byte(filePath),
-		})
-	if err != nil {
-		slog.Error("发布消息到 RabbitMQ 失败", "error", err)
+	if err := h.Queue.Publish(body); err != nil {
+		slog.Error("发布扫描任务到消息队列失败", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "无法发布扫描任务"})
 		return
 	}
 
 	// 返回任务已接收状态
-	ctx.JSON(http.StatusAccepted, gin.H{"message": "文件扫描任务已接收，请稍后查询结果", "md5_low32": md5Low32})
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "文件扫描任务已接收，请稍后查询结果", "md5_low32": md5Low32, "job_id": job.ID})
 }
 
-// 保存 Excel 文件
-func SaveExcelReport(report map[string]interface{}) {
-	slog.Info("开始保存Excel报告")
-	// 提取第一个文件路径作为文件名基础
-	// var baseFileName string
-	// for file := range c.Results {
-	// 	baseFileName = file
-	// 	break
-	// }
-	// if baseFileName == "" {
-	// 	baseFileName = "default"
-	// }
-
-	// // 提取文件名并去掉扩展名
-	// lastSlash := strings.LastIndex(baseFileName, "\\")
-	// if lastSlash == -1 {
-	// 	lastSlash = 0
-	// }
-	// lastDot := strings.LastIndex(baseFileName[lastSlash+1:], ".")
-	// if lastDot == -1 {
-	// 	lastDot = len(baseFileName[lastSlash+1:])
-	// }
-	// fileName := baseFileName[lastSlash+1 : lastSlash+1+lastDot]
-
-	// 构建完整文件路径
-	dir := "results"
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		slog.Error("创建结果目录失败", "error", err)
-		// fmt.Printf("创建结果目录失败: %v\n", err)
-		return
-	}
-	fullPath := filepath.Join(dir, fmt.Sprintf("%s_result.xlsx", report["file-name"]))
-
-	f := excelize.NewFile()
-	sheetName := "CodeAnalysisReport"
-	index, err := f.NewSheet(sheetName)
-	if err != nil {
-		slog.Error("创建 Excel 工作表失败", "error", err)
-		// fmt.Printf("创建 Excel 工作表失败: %v\n", err)
-		return
+// 报告是否使用了全部指定的提示词模板版本
+func usedPrompts(report *models.Report, prompts []string) bool {
+	for _, ref := range prompts {
+		if !slices.Contains(report.Prompts, ref) {
+			return false
+		}
 	}
-	f.SetActiveSheet(index)
+	return true
+}
 
-	// 设置表头
-	headers := []string{"文件", "行号", "规则", "问题描述", "建议修正"}
-	for colIndex, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
-		f.SetCellValue(sheetName, cell, header)
+// 读取请求中的门禁阈值 min_severity（high/medium/low）和 min_confidence（0 到 1）
+func parseGate(ctx *gin.Context) (string, float64, error) {
+	minSeverity := ctx.Query("min_severity")
+	if minSeverity != "" && models.SeverityRank(minSeverity) == 0 {
+		return "", 0, fmt.Errorf("无效的严重级别: %s", minSeverity)
+	}
+	var minConfidence float64
+	if s := ctx.Query("min_confidence"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 1 {
+			return "", 0, fmt.Errorf("无效的置信度: %s", s)
+		}
+		minConfidence = v
 	}
+	return minSeverity, minConfidence, nil
+}
 
-	row := 2
-	for _, issue := range report["issues"].([]map[string]interface{}) {
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), issue["file"])
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), issue["line"])
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), issue["rule"])
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), issue["original"])
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), issue["suggested"])
-		row++
+// 按门禁阈值统计已保存报告中的问题数
+func (h *ScanHandler) evaluateGate(reportModel *models.Report, minSeverity string, minConfidence float64) error {
+	count, err := h.Findings.CountGate(reportModel.ID, minSeverity, minConfidence)
+	if err != nil {
+		return err
 	}
+	reportModel.MinSeverity, reportModel.MinConfidence = minSeverity, minConfidence
+	reportModel.GateIssues = int(count)
+	reportModel.Passed = count == 0
+	return nil
+}
 
-	// 保存 Excel 文件
-	if err := f.SaveAs(fullPath); err != nil {
-		slog.Error("保存 Excel 报告失败", "file", fullPath, "error", err)
-		// fmt.Printf("保存 Excel 报告失败: %v\n", err)
+// 新增下载报告接口
+func (h *ScanHandler) DownloadReport(ctx *gin.Context) {
+	// 优先按查询参数中的任务 ID 查找，否则按请求头中的文件名查找最近一次完成的任务
+	var job *models.ScanJob
+	var err error
+	if jobID := ctx.Query("job_id"); jobID != "" {
+		id, _ := strconv.ParseUint(jobID, 10, 64)
+		if job, err = h.Jobs.Get(uint(id)); err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "扫描任务不存在"})
+			return
+		}
 	} else {
-		slog.Info("Excel 报告已生成", "file", fullPath)
+		fileName := ctx.GetHeader("File-Name")
+		if fileName == "" {
+			slog.Error("未提供文件名")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "未提供文件名"})
+			return
+		}
+		md5Low32 := utils.CalcMd5(filepath.Join(".", "uploads", fileName))
+		if job, err = h.Jobs.LatestDone(md5Low32); err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "报告尚未生成"})
+			return
+		}
 	}
-	slog.Info(" 总计:", report["total_files"], "个文件", report["total_issues"], "处问题")
-	fmt.Printf("\n=== 总计: %d个文件, %d处问题 ===\n", report["total_files"], report["total_issues"])
-	// fmt.Printf("Excel 报告已生成: %s\n", fullPath)
-}
 
-// 新增下载报告接口
-func DownloadReport(ctx *gin.Context) {
-	// 从请求头获取报告文件名
-	fileName := ctx.GetHeader("File-Name")
-	if fileName == "" {
-		slog.Error("未提供文件名")
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未提供文件名"})
+	if job.ReportID == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "报告尚未生成"})
 		return
 	}
-
-	// 构建报告文件路径
-	lastDot := strings.LastIndex(fileName, ".")
-	if lastDot == -1 {
-		lastDot = len(fileName)
+	// 按数据库中的最新状态生成，包含扫描后的人工判定
+	reportModel, err := h.Reports.Get(job.ReportID, true)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "报告不存在"})
+		return
 	}
-	fileName = fileName[:lastDot]
-	reportName := fileName + "_result.xlsx"
-	reportDir := filepath.Join(".", "results")
-	reportPath := filepath.Join(reportDir, reportName)
-
-	// 检查报告文件是否存在
-	if _, err := os.Stat(reportPath); os.IsNotExist(err) {
-		slog.Error("报告文件不存在")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "报告文件不存在"})
+	var buf bytes.Buffer
+	if err := report.WriteExcelReport(&buf, reportModel); err != nil {
+		slog.Error("生成 Excel 报告失败", "job_id", job.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成报告失败"})
 		return
 	}
 
+	baseName := filepath.Base(job.FilePath)
+	reportName := strings.TrimSuffix(baseName, filepath.Ext(baseName)) + "_result.xlsx"
+
 	// 设置响应头
 	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Type", "application/octet-stream")
 	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", reportName))
 	ctx.Header("Content-Transfer-Encoding", "binary")
 	ctx.Header("Expires", "0")
@@ -196,64 +192,24 @@ func DownloadReport(ctx *gin.Context) {
 	ctx.Header("Pragma", "public")
 	ctx.Header("File-Name", reportName)
 
-	// 发送文件
-	ctx.File(reportPath)
-}
-
-// ProcessFileOrDirectory 处理文件或目录的逻辑
-func ProcessFileOrDirectory(filePath string, analyzer *models.CodeAnalyzer) error {
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		slog.Error("获取文件信息失败", "error", err)
-		return err
-	}
-
-	if fileInfo.IsDir() {
-		// 处理目录
-		err := filepath.Walk(filePath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() {
-				return analyzer.ProcessFile(path)
-			}
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("遍历目录失败: %w", err)
-		}
-	} else {
-		// 处理单个文件
-		if err := analyzer.ProcessFile(filePath); err != nil {
-			return fmt.Errorf("处理文件失败: %w", err)
-		}
-	}
-
-	// 检查 Ollama LLM 是否启动
-	req, err := http.NewRequest("GET", "http://localhost:11434/api/tags", nil)
-	if err != nil {
-		slog.Error("创建请求失败", "error", err)
-		return err
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		slog.Error("Ollama LLM 未启动")
-		return fmt.Errorf("Ollama LLM 未启动")
-	}
-	return nil
+	ctx.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
 }
 
-func CheckReport(ctx *gin.Context) {
+func (h *ScanHandler) CheckReport(ctx *gin.Context) {
 	md5Low32 := ctx.Query("md5_low32")
 	if md5Low32 == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未提供 md5_low32"})
 		return
 	}
 
-	var reportModel models.Report
-	if err := global.Db.Where("md5_low32 = ?", md5Low32).First(&reportModel).Error; err == nil {
+	if reportModel, err := h.Reports.Latest(md5Low32, false); err == nil {
 		ctx.JSON(http.StatusOK, reportModel)
 	} else {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "报告尚未生成"})
 	}
 }
+
+// 查询各 LLM 服务的健康状态
+func (h *ScanHandler) GetLLMProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"providers": h.Providers.Status(), "available": h.Providers.Available()})
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"net/http"
+	"standardizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列出提示词模板库
+func (h *ReportHandler) GetPrompts(ctx *gin.Context) {
+	library := h.Prompts
+	if library == nil {
+		library = &models.PromptLibrary{}
+	}
+	ctx.JSON(http.StatusOK, library)
+}
+
+// 按提示词模板版本统计 LLM 发现的问题及人工判定结果，用于比较不同版本的效果
+//
+//	GET /api/prompts/stats?rule_set=gjb&rule=规则2
+func (h *ReportHandler) GetPromptStats(ctx *gin.Context) {
+	stats, err := h.Findings.PromptStats(ctx.Query("rule_set"), splitQueryList(ctx.Query("rule")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"prompts": stats})
+}
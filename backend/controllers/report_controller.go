@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"standardizer/models"
+	"standardizer/report"
+	"standardizer/repository"
+	"standardizer/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 下载 HTML 报告，可直接打印为 PDF
+func (h *ReportHandler) DownloadHTMLReport(ctx *gin.Context) {
+	// 优先使用查询参数中的 md5_low32，否则按请求头中的文件名计算
+	md5Low32 := ctx.Query("md5_low32")
+	fileName := ctx.GetHeader("File-Name")
+	if md5Low32 == "" {
+		if fileName == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "未提供文件名"})
+			return
+		}
+		filePath := filepath.Join(".", "uploads", fileName)
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			slog.Error("文件不存在", "file", filePath)
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+			return
+		}
+		md5Low32 = utils.CalcMd5(filePath)
+	}
+
+	reportModel, err := h.Reports.Latest(md5Low32, true)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "报告尚未生成"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.RenderHTML(&buf, reportModel); err != nil {
+		slog.Error("渲染 HTML 报告失败", "report_id", reportModel.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成报告失败"})
+		return
+	}
+
+	baseName := reportModel.FileName
+	if baseName == "" {
+		baseName = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	reportName := baseName + "_result.html"
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", reportName))
+	ctx.Header("File-Name", reportName)
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// 下载报告中所有可应用修复的统一 diff 补丁，可用 file 参数只导出单个文件，verified=true 时只导出通过验证的修复
+//
+//	GET /api/reports/:id/patch?file=src/main.cpp&verified=true
+func (h *ReportHandler) DownloadPatch(ctx *gin.Context) {
+	reportModel, patches, ok := h.loadFilePatches(ctx)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.WritePatch(&buf, patches); err != nil {
+		slog.Error("生成修复补丁失败", "report_id", reportModel.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成补丁失败"})
+		return
+	}
+	if buf.Len() == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "没有可应用的修复"})
+		return
+	}
+
+	patchName := reportModel.FileName + "_fixes.patch"
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", patchName))
+	ctx.Header("File-Name", patchName)
+	ctx.Data(http.StatusOK, "text/x-diff; charset=utf-8", buf.Bytes())
+}
+
+// 下载应用全部可应用修复后的源文件
+//
+//	GET /api/reports/:id/fixed-file?file=src/main.cpp&verified=true
+func (h *ReportHandler) DownloadFixedFile(ctx *gin.Context) {
+	if ctx.Query("file") == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未提供文件路径"})
+		return
+	}
+	_, patches, ok := h.loadFilePatches(ctx)
+	if !ok {
+		return
+	}
+	if len(patches) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "该文件没有修复"})
+		return
+	}
+
+	content, applied, err := report.FixedFile(patches[0])
+	if err != nil {
+		slog.Error("读取源文件失败", "file", patches[0].Path, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "读取源文件失败"})
+		return
+	}
+	fileName := filepath.Base(patches[0].Name)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	ctx.Header("File-Name", fileName)
+	ctx.Header("X-Applied-Fixes", strconv.Itoa(len(applied)))
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+}
+
+// 读取报告中未屏蔽问题的修复，按文件分组；file 参数为扫描根目录下的相对路径
+func (h *ReportHandler) loadFilePatches(ctx *gin.Context) (*models.Report, []report.FilePatch, bool) {
+	reportModel, err := h.Reports.Get(paramID(ctx), false)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "报告不存在"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, nil, false
+	}
+	job, err := h.Jobs.Get(reportModel.JobID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "扫描任务不存在"})
+		return nil, nil, false
+	}
+
+	findings, err := h.Findings.ListByReport(reportModel.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	only := filepath.ToSlash(ctx.Query("file"))
+	verifiedOnly := ctx.Query("verified") == "true"
+	var patches []report.FilePatch
+	for i := range findings {
+		f := &findings[i]
+		if f.Fix == nil || f.Suppressed() || verifiedOnly && !f.Fix.Verified {
+			continue
+		}
+		name := patchFileName(job.FilePath, f.File)
+		if only != "" && name != only {
+			continue
+		}
+		if n := len(patches); n == 0 || patches[n-1].Path != f.File {
+			patches = append(patches, report.FilePatch{Name: name, Path: f.File})
+		}
+		patches[len(patches)-1].Fixes = append(patches[len(patches)-1].Fixes, f.Fix)
+	}
+	return reportModel, patches, true
+}
+
+// 补丁中的文件路径：相对扫描目录，扫描单个文件时使用文件名
+func patchFileName(root, file string) string {
+	if info, err := os.Stat(root); err == nil && info.IsDir() {
+		if rel, err := filepath.Rel(root, file); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(file)
+}
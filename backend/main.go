@@ -6,15 +6,39 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"standardizer/app"
+	"standardizer/benchmark"
 	"standardizer/config"
-	"standardizer/router"
+	"standardizer/migrations"
 	"time"
 )
 
 func main() {
-	config.InitConfig()
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "benchmark":
+			if err := benchmark.Run(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "migrate":
+			if err := migrations.Run(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
 
-	r := router.SetupRouter()
+	config.LoadConfig()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	application, err := app.New(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer application.Close()
+	application.Start()
 
 	port := config.AppConfig.App.Port
 	if port == "" {
@@ -23,7 +47,7 @@ func main() {
 
 	srv := &http.Server{
 		Addr:    port,
-		Handler: r,
+		Handler: application.Router,
 	}
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -36,9 +60,10 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal("Server shutdown:", err)
 	}
 	log.Println("Server exiting")
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 用户、文章和汇率。此前由各接口在请求中自动迁移创建，已有的表会被沿用
+var createBaseTables = Migration{
+	Version: 1,
+	Name:    "create_base_tables",
+	Creates: []interface{}{&v1User{}, &v1Article{}, &v1ExchangeRate{}},
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&v1User{}, &v1Article{}, &v1ExchangeRate{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&v1ExchangeRate{}, &v1Article{}, &v1User{})
+	},
+}
+
+type v1User struct {
+	gorm.Model
+	Name     string `gorm:"unique"`
+	Password string
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Article struct {
+	gorm.Model
+	Title   string
+	Content string
+	Preview string
+}
+
+func (v1Article) TableName() string { return "articles" }
+
+type v1ExchangeRate struct {
+	ID           uint `gorm:"primary_key"`
+	FromCurrency string
+	ToCurrency   string
+	Rate         float64
+	Date         time.Time
+}
+
+func (v1ExchangeRate) TableName() string { return "exchange_rates" }
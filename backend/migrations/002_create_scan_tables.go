@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 扫描任务、报告、检查发现、屏蔽注释、修复和人工判定
+var createScanTables = Migration{
+	Version: 2,
+	Name:    "create_scan_tables",
+	Creates: []interface{}{&v2ScanJob{}, &v2Report{}, &v2Finding{}, &v2Suppression{}, &v2FindingTriage{}, &v2Fix{}},
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&v2ScanJob{}, &v2Report{}, &v2Finding{}, &v2Suppression{}, &v2FindingTriage{}, &v2Fix{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&v2Fix{}, &v2FindingTriage{}, &v2Suppression{}, &v2Finding{}, &v2Report{}, &v2ScanJob{})
+	},
+}
+
+type v2ScanJob struct {
+	gorm.Model
+	FilePath      string
+	MD5Low32      string `gorm:"size:32;index"`
+	UserName      string
+	Status        string `gorm:"size:16"`
+	Error         string
+	ReportID      uint
+	MinSeverity   string `gorm:"size:16"`
+	MinConfidence float64
+	LLMCalls      int
+	QueueWaitMs   int64
+}
+
+func (v2ScanJob) TableName() string { return "scan_jobs" }
+
+type v2Report struct {
+	ID            uint `gorm:"primaryKey"`
+	SchemaVersion int
+	JobID         uint   `gorm:"index"`
+	MD5Low32      string `gorm:"size:32;index"`
+	FileName      string
+	Title         string
+	RuleSet       string
+	RuleCount     int
+	Rules         string `gorm:"type:text"`
+	Prompts       string `gorm:"type:text"`
+	TotalFiles    int
+	TotalIssues   int
+	Suppressed    int
+	MinSeverity   string `gorm:"size:16"`
+	MinConfidence float64
+	GateIssues    int
+	Passed        bool
+	Files         string          `gorm:"type:text"`
+	Findings      []v2Finding     `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE"`
+	Suppressions  []v2Suppression `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (v2Report) TableName() string { return "reports" }
+
+type v2Finding struct {
+	ID            uint   `gorm:"primaryKey"`
+	ReportID      uint   `gorm:"index"`
+	File          string `gorm:"size:512;index"`
+	Line          int
+	Rule          string `gorm:"size:64;index"`
+	Severity      string `gorm:"size:16;index"`
+	Rank          int    `gorm:"column:severity_rank;index"`
+	Status        string `gorm:"size:16;default:open;index"`
+	Original      string `gorm:"type:text"`
+	Suggested     string `gorm:"type:text"`
+	CreatedAt     time.Time
+	Fingerprint   string `gorm:"size:40;index"`
+	Justification string `gorm:"type:text"`
+	TriagedBy     string
+	TriagedAt     *time.Time
+	Votes         int
+	Confidence    float64
+	StartColumn   int
+	EndColumn     int
+	Snippet       string `gorm:"type:text"`
+	Engine        string `gorm:"size:16;index"`
+	Prompt        string `gorm:"size:128;index"`
+	Fix           *v2Fix `gorm:"foreignKey:FindingID;constraint:OnDelete:CASCADE"`
+}
+
+func (v2Finding) TableName() string { return "findings" }
+
+type v2Suppression struct {
+	ID       uint   `gorm:"primaryKey"`
+	ReportID uint   `gorm:"index"`
+	File     string `gorm:"size:512"`
+	Line     int
+	Comment  int
+	Scope    string `gorm:"size:8"`
+	Rules    string
+	Reason   string `gorm:"type:text"`
+	Matched  int
+}
+
+func (v2Suppression) TableName() string { return "suppressions" }
+
+type v2FindingTriage struct {
+	ID            uint   `gorm:"primaryKey"`
+	FindingID     uint   `gorm:"index"`
+	Fingerprint   string `gorm:"size:40;index"`
+	FromStatus    string `gorm:"size:16"`
+	Status        string `gorm:"size:16"`
+	Justification string `gorm:"type:text"`
+	UserName      string
+	CreatedAt     time.Time
+}
+
+func (v2FindingTriage) TableName() string { return "finding_triages" }
+
+type v2Fix struct {
+	ID          uint `gorm:"primaryKey"`
+	FindingID   uint `gorm:"index"`
+	StartLine   int
+	EndLine     int
+	Original    string `gorm:"type:text"`
+	Replacement string `gorm:"type:text"`
+	Source      string `gorm:"size:16"`
+	Applicable  bool
+	Error       string `gorm:"type:text"`
+	Verified    bool
+	VerifyNote  string `gorm:"type:text"`
+}
+
+func (v2Fix) TableName() string { return "fixes" }
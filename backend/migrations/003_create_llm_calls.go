@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LLM 调用用量记录
+var createLLMCalls = Migration{
+	Version: 3,
+	Name:    "create_llm_calls",
+	Creates: []interface{}{&v3LLMCall{}},
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&v3LLMCall{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&v3LLMCall{})
+	},
+}
+
+type v3LLMCall struct {
+	ID               uint      `gorm:"primaryKey"`
+	CreatedAt        time.Time `gorm:"index"`
+	JobID            uint      `gorm:"index"`
+	Model            string    `gorm:"size:64;index"`
+	Purpose          string    `gorm:"size:16"`
+	RuleSet          string    `gorm:"size:64;index"`
+	File             string
+	PromptTokens     int
+	CompletionTokens int
+	Estimated        bool
+	WaitMs           int64
+	LatencyMs        int64
+	Retries          int
+	Outcome          string `gorm:"size:16;index"`
+	Error            string `gorm:"type:text"`
+}
+
+func (v3LLMCall) TableName() string { return "llm_calls" }
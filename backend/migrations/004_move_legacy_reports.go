@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 旧版本的报告只保存 content 文本并使用软删除，迁移 2 沿用 reports 表后这些列和数据仍留在表中。
+// 将旧报告移到 legacy_reports 表并删除 reports 中的旧列，回滚时移回
+var moveLegacyReports = Migration{
+	Version: 4,
+	Name:    "move_legacy_reports",
+	Up: func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		if !migrator.HasColumn(&v4ReportColumns{}, "Content") && !migrator.HasColumn(&v4ReportColumns{}, "DeletedAt") {
+			return nil
+		}
+		for _, column := range []string{"Content", "DeletedAt"} {
+			if !migrator.HasColumn(&v4ReportColumns{}, column) {
+				if err := migrator.AddColumn(&v4ReportColumns{}, column); err != nil {
+					return err
+				}
+			}
+		}
+		if err := migrator.CreateTable(&v4LegacyReport{}); err != nil {
+			return err
+		}
+		if err := tx.Exec(`INSERT INTO legacy_reports (id, md5_low32, content, created_at, updated_at, deleted_at)
+			SELECT id, md5_low32, content, created_at, updated_at, deleted_at FROM reports
+			WHERE schema_version IS NULL OR schema_version < 1`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM reports WHERE schema_version IS NULL OR schema_version < 1").Error; err != nil {
+			return err
+		}
+		if migrator.HasIndex(&v4ReportColumns{}, "idx_reports_deleted_at") {
+			if err := migrator.DropIndex(&v4ReportColumns{}, "idx_reports_deleted_at"); err != nil {
+				return err
+			}
+		}
+		for _, column := range []string{"Content", "DeletedAt"} {
+			if err := migrator.DropColumn(&v4ReportColumns{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		if !migrator.HasTable(&v4LegacyReport{}) {
+			return nil
+		}
+		for _, column := range []string{"Content", "DeletedAt"} {
+			if err := migrator.AddColumn(&v4ReportColumns{}, column); err != nil {
+				return err
+			}
+		}
+		if err := migrator.CreateIndex(&v4ReportColumns{}, "DeletedAt"); err != nil {
+			return err
+		}
+		if err := tx.Exec(`INSERT INTO reports (id, schema_version, md5_low32, content, created_at, updated_at, deleted_at)
+			SELECT id, 0, md5_low32, content, created_at, updated_at, deleted_at FROM legacy_reports`).Error; err != nil {
+			return err
+		}
+		return migrator.DropTable(&v4LegacyReport{})
+	},
+}
+
+// 旧版本报告
+type v4LegacyReport struct {
+	ID        uint   `gorm:"primaryKey"`
+	MD5Low32  string `gorm:"size:32;index"`
+	Content   string `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (v4LegacyReport) TableName() string { return "legacy_reports" }
+
+// reports 表中旧版本遗留的列
+type v4ReportColumns struct {
+	Content   string         `gorm:"type:text"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v4ReportColumns) TableName() string { return "reports" }
@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 扫描任务记录请求指定的提示词模板版本
+var addScanJobPrompts = Migration{
+	Version: 5,
+	Name:    "add_scan_job_prompts",
+	Up: func(tx *gorm.DB) error {
+		if tx.Migrator().HasColumn(&v5ScanJob{}, "Prompts") {
+			return nil
+		}
+		return tx.Migrator().AddColumn(&v5ScanJob{}, "Prompts")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&v5ScanJob{}, "Prompts")
+	},
+}
+
+type v5ScanJob struct {
+	Prompts string `gorm:"type:text"`
+}
+
+func (v5ScanJob) TableName() string { return "scan_jobs" }
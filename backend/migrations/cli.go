@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"standardizer/config"
+
+	"gorm.io/gorm"
+)
+
+// migrate 子命令：
+//
+//	migrate [up] [-to 版本]   执行未执行的迁移，默认执行到最新版本
+//	migrate down [-to 版本]   回滚迁移，默认只回滚最近一次，-to 0 回滚全部；沿用了已有表的迁移不能回滚
+//	migrate status           列出各迁移的执行状态
+func Run(args []string) error {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	to := fs.Int("to", -1, "目标版本")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config.LoadConfig()
+	db, err := config.OpenDB()
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch action {
+	case "up":
+		target := max(*to, 0)
+		ran, err := Up(db, target)
+		printMigrations(os.Stdout, "已执行", ran)
+		return err
+	case "down":
+		target := *to
+		if target < 0 {
+			current, err := Current(db)
+			if err != nil {
+				return err
+			}
+			target = previousVersion(current)
+		}
+		ran, err := Down(db, target)
+		printMigrations(os.Stdout, "已回滚", ran)
+		return err
+	case "status":
+		return printStatus(os.Stdout, db)
+	default:
+		return fmt.Errorf("未知的迁移命令: %s", action)
+	}
+}
+
+// 版本号小于 version 的最大迁移版本，没有时为 0
+func previousVersion(version int) int {
+	previous := 0
+	for _, m := range all {
+		if m.Version < version {
+			previous = m.Version
+		}
+	}
+	return previous
+}
+
+func printMigrations(w io.Writer, verb string, ran []Migration) {
+	if len(ran) == 0 {
+		fmt.Fprintln(w, "没有需要处理的迁移")
+		return
+	}
+	for _, m := range ran {
+		fmt.Fprintf(w, "%s %d %s\n", verb, m.Version, m.Name)
+	}
+}
+
+func printStatus(w io.Writer, db *gorm.DB) error {
+	statuses, err := List(db)
+	if err != nil {
+		return err
+	}
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "当前版本 %d，最新版本 %d\n", current, Latest())
+	for _, s := range statuses {
+		state := "未执行"
+		if s.AppliedAt != nil {
+			state = "已执行于 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			if s.Adopted {
+				state += "，沿用已有表，不能回滚"
+			}
+		}
+		fmt.Fprintf(w, "%4d  %-24s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
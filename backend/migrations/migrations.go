@@ -0,0 +1,241 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 一次数据库结构变更。Up 和 Down 必须使用迁移文件中固定的表结构，
+// 不能引用 models 中会继续变化的模型
+type Migration struct {
+	Version int
+	Name    string
+	// 迁移创建的表。执行时其中已有表存在说明迁移沿用了原有数据，这样的迁移不能回滚
+	Creates []interface{}
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// 已执行的迁移记录
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:128"`
+	AppliedAt time.Time
+	// 执行时沿用了已存在的表，为空表示记录早于该字段，按沿用处理
+	Adopted *bool
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// 迁移的执行状态
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Adopted   bool
+}
+
+var (
+	// 数据库结构落后于程序
+	ErrSchemaBehind = errors.New("数据库结构版本落后")
+	// 回滚会删除迁移前已存在的表及其数据
+	ErrAdopted = errors.New("迁移沿用了已存在的表，不能回滚")
+)
+
+// 全部迁移，按版本号排序
+var all = []Migration{
+	createBaseTables,
+	createScanTables,
+	createLLMCalls,
+	moveLegacyReports,
+	addScanJobPrompts,
+}
+
+func init() {
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i := 1; i < len(all); i++ {
+		if all[i].Version == all[i-1].Version {
+			panic(fmt.Sprintf("迁移版本号重复: %d", all[i].Version))
+		}
+	}
+}
+
+// 程序需要的数据库结构版本
+func Latest() int {
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+// 已执行的迁移，迁移记录表不存在时为空
+func applied(db *gorm.DB) (map[int]schemaMigration, error) {
+	result := make(map[int]schemaMigration)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return result, nil
+	}
+	var records []schemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		result[r.Version] = r
+	}
+	return result, nil
+}
+
+// 当前数据库结构版本，即已执行的最大版本号
+func Current(db *gorm.DB) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range done {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// 各迁移的执行状态
+func List(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(all))
+	for _, m := range all {
+		s := Status{Version: m.Version, Name: m.Name}
+		if r, ok := done[m.Version]; ok {
+			appliedAt := r.AppliedAt
+			s.AppliedAt = &appliedAt
+			s.Adopted = m.adopted(r)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// 检查数据库结构是否为最新，有未执行的迁移时返回 ErrSchemaBehind
+func Check(db *gorm.DB) error {
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	var pending []int
+	for _, m := range all {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: 未执行的迁移 %v，请先运行 migrate up", ErrSchemaBehind, pending)
+	}
+	for version := range done {
+		if version > Latest() {
+			slog.Warn("数据库结构版本高于程序", "version", version, "latest", Latest())
+			break
+		}
+	}
+	return nil
+}
+
+// 按版本号依次执行未执行的迁移，直到 target（含），target 为 0 时执行全部迁移
+func Up(db *gorm.DB, target int) ([]Migration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for _, m := range all {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		adopted := m.existing(db)
+		if len(adopted) > 0 {
+			slog.Info("沿用已存在的表", "version", m.Version, "tables", adopted)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			record := &schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now(), Adopted: new(bool)}
+			*record.Adopted = len(adopted) > 0
+			return tx.Create(record).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("执行迁移 %d %s 失败: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// 按版本号倒序回滚已执行的迁移，直到版本号不大于 target，target 为 0 时回滚全部迁移。
+// 遇到沿用了已存在表的迁移时停止并返回 ErrAdopted，不删除原有的表
+func Down(db *gorm.DB, target int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= target {
+			break
+		}
+		r, ok := done[m.Version]
+		if !ok {
+			continue
+		}
+		if m.adopted(r) {
+			return ran, fmt.Errorf("%w: %d %s", ErrAdopted, m.Version, m.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("回滚迁移 %d %s 失败: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// 迁移要创建的表中已存在的表
+func (m Migration) existing(db *gorm.DB) []string {
+	var tables []string
+	for _, table := range m.Creates {
+		if db.Migrator().HasTable(table) {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(table); err == nil {
+				tables = append(tables, stmt.Table)
+			}
+		}
+	}
+	return tables
+}
+
+// 执行记录表明迁移沿用了已存在的表
+func (m Migration) adopted(r schemaMigration) bool {
+	if len(m.Creates) == 0 {
+		return false
+	}
+	return r.Adopted == nil || *r.Adopted
+}
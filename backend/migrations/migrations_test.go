@@ -0,0 +1,270 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 空的内存 SQLite 数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+var allTables = []string{"users", "articles", "exchange_rates", "scan_jobs", "reports", "findings", "suppressions", "finding_triages", "fixes", "llm_calls"}
+
+func versions(ran []Migration) []int {
+	var result []int
+	for _, m := range ran {
+		result = append(result, m.Version)
+	}
+	return result
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func mustCurrent(t *testing.T, db *gorm.DB, want int) {
+	t.Helper()
+	current, err := Current(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != want {
+		t.Fatalf("current version %d, want %d", current, want)
+	}
+}
+
+func TestUpDownFreshDatabase(t *testing.T) {
+	db := newTestDB(t)
+	if err := Check(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check on empty database: got %v, want ErrSchemaBehind", err)
+	}
+
+	ran, err := Up(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(versions(ran), []int{1, 2}) {
+		t.Fatalf("ran %v, want [1 2]", versions(ran))
+	}
+	if err := Check(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check at version 2: got %v, want ErrSchemaBehind", err)
+	}
+
+	ran, err = Up(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(versions(ran), []int{3, 4, 5}) {
+		t.Fatalf("ran %v, want [3 4 5]", versions(ran))
+	}
+	if err := Check(db); err != nil {
+		t.Fatal(err)
+	}
+	mustCurrent(t, db, Latest())
+	for _, table := range allTables {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s missing after up", table)
+		}
+	}
+	if !db.Migrator().HasColumn("scan_jobs", "prompts") {
+		t.Error("scan_jobs.prompts missing after up")
+	}
+	if db.Migrator().HasTable("legacy_reports") {
+		t.Error("legacy_reports created on a fresh database")
+	}
+	statuses, err := List(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil || s.Adopted {
+			t.Errorf("status %+v, want applied and not adopted", s)
+		}
+	}
+
+	// 再次执行没有需要处理的迁移
+	if ran, err = Up(db, 0); err != nil || len(ran) != 0 {
+		t.Fatalf("second up ran %v, %v", versions(ran), err)
+	}
+
+	ran, err = Down(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(versions(ran), []int{5, 4, 3, 2, 1}) {
+		t.Fatalf("rolled back %v, want [5 4 3 2 1]", versions(ran))
+	}
+	mustCurrent(t, db, 0)
+	for _, table := range allTables {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s left after down", table)
+		}
+	}
+
+	if _, err := Up(db, 0); err != nil {
+		t.Fatalf("up after full down: %v", err)
+	}
+	mustCurrent(t, db, Latest())
+}
+
+// 迁移工具引入前由接口自动迁移创建的表
+type legacyUser struct {
+	gorm.Model
+	Name     string `gorm:"unique"`
+	Password string
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+type legacyReport struct {
+	gorm.Model
+	MD5Low32  string `gorm:"size:32"`
+	Content   string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (legacyReport) TableName() string { return "reports" }
+
+func createLegacySchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.AutoMigrate(&legacyUser{}, &legacyReport{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&legacyUser{Name: "alice", Password: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&legacyReport{MD5Low32: "old", Content: "旧报告"}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, table string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Table(table).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestUpAdoptsExistingSchema(t *testing.T) {
+	db := newTestDB(t)
+	createLegacySchema(t, db)
+
+	if _, err := Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// 原有用户保留，旧报告移到 legacy_reports，reports 中不再有旧列
+	if n := count(t, db, "users"); n != 1 {
+		t.Errorf("users has %d rows, want 1", n)
+	}
+	if n := count(t, db, "reports"); n != 0 {
+		t.Errorf("reports has %d rows, want legacy rows moved out", n)
+	}
+	var content string
+	if err := db.Table("legacy_reports").Select("content").Where("md5_low32 = ?", "old").Scan(&content).Error; err != nil || content != "旧报告" {
+		t.Errorf("legacy report content %q, %v", content, err)
+	}
+	for _, column := range []string{"content", "deleted_at"} {
+		if db.Migrator().HasColumn("reports", column) {
+			t.Errorf("reports still has legacy column %s", column)
+		}
+	}
+
+	statuses, err := List(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAdopted := map[int]bool{1: true, 2: true, 3: false, 4: false, 5: false}
+	for _, s := range statuses {
+		if s.Adopted != wantAdopted[s.Version] {
+			t.Errorf("migration %d adopted=%v, want %v", s.Version, s.Adopted, wantAdopted[s.Version])
+		}
+	}
+
+	// 回滚到沿用了已有表的迁移时停止，不删除原有的表
+	ran, err := Down(db, 0)
+	if !errors.Is(err, ErrAdopted) {
+		t.Fatalf("down to 0: got %v, want ErrAdopted", err)
+	}
+	if !equalInts(versions(ran), []int{5, 4, 3}) {
+		t.Fatalf("rolled back %v, want [5 4 3]", versions(ran))
+	}
+	mustCurrent(t, db, 2)
+	if n := count(t, db, "users"); n != 1 {
+		t.Errorf("users has %d rows after down, want 1", n)
+	}
+	if db.Migrator().HasTable("llm_calls") {
+		t.Error("llm_calls created by migration 3 not dropped")
+	}
+
+	// 回滚迁移 4 后旧报告回到 reports
+	if db.Migrator().HasTable("legacy_reports") {
+		t.Error("legacy_reports left after rolling back migration 4")
+	}
+	var restored legacyReport
+	if err := db.Where("md5_low32 = ?", "old").First(&restored).Error; err != nil || restored.Content != "旧报告" {
+		t.Errorf("restored legacy report %+v, %v", restored, err)
+	}
+}
+
+func TestLegacyReportsRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	createLegacySchema(t, db)
+	if _, err := Up(db, 3); err != nil {
+		t.Fatal(err)
+	}
+	// 迁移 2 沿用 reports 后新报告与旧报告混在一起
+	if err := db.Exec("INSERT INTO reports (schema_version, md5_low32, file_name) VALUES (1, 'new', 'a.cpp')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := Up(db, 0); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "reports"); n != 1 {
+			t.Fatalf("reports has %d rows after up, want only the new report", n)
+		}
+		if n := count(t, db, "legacy_reports"); n != 1 {
+			t.Fatalf("legacy_reports has %d rows, want 1", n)
+		}
+		if _, err := Down(db, 3); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "reports"); n != 2 {
+			t.Fatalf("reports has %d rows after down, want 2", n)
+		}
+	}
+}
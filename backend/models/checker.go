@@ -0,0 +1,297 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 静态检查器，对单条规则做确定性的模式检查，补充 LLM 的分析结果
+type StaticChecker struct {
+	Rule  string // 检查的规则编号
+	Check func(file string, lines []string) []Issue
+	// 可选的单行修复，无法修复时返回空字符串
+	Fix func(line string, lang *Language) string
+}
+
+// 对文件运行适用规则对应的静态检查器
+func RunStaticCheckers(lang *Language, file string, lines []string, rules []Rule) []Issue {
+	enabled := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		enabled[r.ID] = true
+	}
+	var issues []Issue
+	for _, checker := range lang.Checkers {
+		if enabled[checker.Rule] {
+			issues = append(issues, checker.Check(file, lines)...)
+		}
+	}
+	for i := range issues {
+		issues[i].Engine = EngineStatic
+	}
+	return issues
+}
+
+// 匹配位置 [start, end) 对应的列范围，列号从 1 开始按字符计算，包含结束列
+func columnRange(line string, start, end int) (int, int) {
+	return utf8.RuneCountInString(line[:start]) + 1, utf8.RuneCountInString(line[:end])
+}
+
+// 字符串和字符字面量
+var stringLiteralRe = regexp.MustCompile(`"(?:\\.|[^"\\])*"|'(?:\\.|[^'\\])*'`)
+
+// 清空字符串字面量的内容，保持字节位置不变以便计算列号
+func blankLiterals(line string) string {
+	return stringLiteralRe.ReplaceAllStringFunc(line, func(lit string) string {
+		return lit[:1] + strings.Repeat(" ", len(lit)-2) + lit[len(lit)-1:]
+	})
+}
+
+// 去掉 C/C++ 行中的字符串字面量和注释，块注释按行粗略处理，注释替换为等长空白
+func stripCLine(line string, inBlock *bool) string {
+	if *inBlock {
+		end := strings.Index(line, "*/")
+		if end < 0 {
+			return ""
+		}
+		line = strings.Repeat(" ", end+2) + line[end+2:]
+		*inBlock = false
+	}
+	line = blankLiterals(line)
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	for {
+		start := strings.Index(line, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(line[start+2:], "*/")
+		if end < 0 {
+			line = line[:start]
+			*inBlock = true
+			break
+		}
+		line = line[:start] + strings.Repeat(" ", end+4) + line[start+2+end+2:]
+	}
+	return line
+}
+
+func stripCLines(lines []string) []string {
+	inBlock := false
+	stripped := make([]string, len(lines))
+	for i, line := range lines {
+		stripped[i] = stripCLine(line, &inBlock)
+	}
+	return stripped
+}
+
+func stripPyLine(line string) string {
+	line = blankLiterals(line)
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	return line
+}
+
+var (
+	signedDeclRe = regexp.MustCompile(`\b(?:signed\s+|const\s+)?(?:int|long|short|char|signed|int8_t|int16_t|int32_t|int64_t|ptrdiff_t|ssize_t)\s+([A-Za-z_]\w*)\s*(?:=|;|,|\))`)
+	indexRe      = regexp.MustCompile(`\w\s*\[\s*([A-Za-z_]\w*)\s*\]`)
+)
+
+// 规则1：数组索引使用了有符号类型的变量
+var checkSignedIndex = StaticChecker{
+	Rule: "规则1",
+	Check: func(file string, lines []string) []Issue {
+		code := stripCLines(lines)
+		signed := make(map[string]bool)
+		for _, line := range code {
+			for _, m := range signedDeclRe.FindAllStringSubmatch(line, -1) {
+				signed[m[1]] = true
+			}
+		}
+		var issues []Issue
+		for i, line := range code {
+			for _, m := range indexRe.FindAllStringSubmatchIndex(line, -1) {
+				name := line[m[2]:m[3]]
+				if signed[name] {
+					startCol, endCol := columnRange(lines[i], m[2], m[3])
+					issues = append(issues, Issue{
+						File:        file,
+						Line:        i + 1,
+						Rule:        "规则1",
+						Original:    fmt.Sprintf("使用有符号变量%s作为数组索引", name),
+						Suggested:   fmt.Sprintf("将%s声明为size_t等无符号类型", name),
+						StartColumn: startCol,
+						EndColumn:   endCol,
+					})
+					break
+				}
+			}
+		}
+		return issues
+	},
+}
+
+var cCastRe = regexp.MustCompile(`(?:^|[=(,\s])\(\s*((?:const\s+|unsigned\s+|signed\s+)*(?:int|long|short|char|float|double|bool|size_t|u?int(?:8|16|32|64)_t|void)(?:\s*\*+)?)\s*\)\s*[A-Za-z_(&*]`)
+
+// 行中第一个 C 风格类型转换，sizeof (T) 不是类型转换
+func findCCast(line string) []int {
+	for _, m := range cCastRe.FindAllStringSubmatchIndex(line, -1) {
+		lparen, _ := castParens(line, m)
+		if !strings.HasSuffix(strings.TrimRight(line[:lparen], " \t"), "sizeof") {
+			return m
+		}
+	}
+	return nil
+}
+
+// cCastRe 匹配中类型转换左右括号的位置，匹配可能从转换之前的左括号开始
+func castParens(line string, m []int) (int, int) {
+	return strings.LastIndex(line[:m[2]], "("), strings.Index(line[m[3]:], ")") + m[3]
+}
+
+// 规则2：C 风格强制类型转换
+var checkCStyleCast = StaticChecker{
+	Rule: "规则2",
+	Check: func(file string, lines []string) []Issue {
+		var issues []Issue
+		for i, line := range stripCLines(lines) {
+			m := findCCast(line)
+			if m == nil {
+				continue
+			}
+			typ := line[m[2]:m[3]]
+			lparen, rparen := castParens(line, m)
+			startCol, endCol := columnRange(lines[i], lparen, rparen+1)
+			issues = append(issues, Issue{
+				File:        file,
+				Line:        i + 1,
+				Rule:        "规则2",
+				Original:    fmt.Sprintf("C风格强制类型转换(%s)", typ),
+				Suggested:   fmt.Sprintf("使用static_cast<%s>(...)等C++风格转换", typ),
+				StartColumn: startCol,
+				EndColumn:   endCol,
+			})
+		}
+		return issues
+	},
+	Fix: fixCStyleCast,
+}
+
+var (
+	uninitPtrRe = regexp.MustCompile(`^\s*(?:static\s+|const\s+|volatile\s+)*[A-Za-z_][\w:<>]*\s*\*+\s*(?:const\s+)?([A-Za-z_]\w*)\s*;`)
+	// class/struct/union 定义的开头，花括号可以在下一行；模板参数中的 class T 和 struct T *p; 之类的声明不匹配
+	classHeadRe = regexp.MustCompile(`\b(?:class|struct|union)\b(?:\s+[A-Za-z_]\w*)?\s*(?:final\s*)?(?::[^;{]*)?(?:\{|$)`)
+)
+
+// 标记直接位于 class/struct/union 定义体中的行，成员函数体中的行不算
+func classBodyLines(code []string) []bool {
+	inClass := make([]bool, len(code))
+	var scopes []bool // 每层花括号是否为类定义体
+	pending := false  // 已读到类定义的开头，等待左花括号
+	for i, line := range code {
+		inClass[i] = len(scopes) > 0 && scopes[len(scopes)-1]
+		head := classHeadRe.FindStringIndex(line)
+		for j := 0; j < len(line); j++ {
+			if head != nil && j == head[0] {
+				pending = true
+			}
+			switch line[j] {
+			case '{':
+				scopes = append(scopes, pending)
+				pending = false
+			case '}':
+				if len(scopes) > 0 {
+					scopes = scopes[:len(scopes)-1]
+				}
+			}
+		}
+	}
+	return inClass
+}
+
+// 规则3：声明指针时未初始化
+var checkUninitializedPointer = StaticChecker{
+	Rule: "规则3",
+	Check: func(file string, lines []string) []Issue {
+		var issues []Issue
+		code := stripCLines(lines)
+		// 类的成员指针通常在构造函数中初始化，不按未初始化处理
+		inClass := classBodyLines(code)
+		for i, line := range code {
+			m := uninitPtrRe.FindStringSubmatchIndex(line)
+			// 排除 return *p; 之类的语句
+			if m == nil || inClass[i] || strings.HasPrefix(strings.TrimSpace(line), "return") {
+				continue
+			}
+			name := line[m[2]:m[3]]
+			startCol, endCol := columnRange(lines[i], m[2], m[3])
+			issues = append(issues, Issue{
+				File:        file,
+				Line:        i + 1,
+				Rule:        "规则3",
+				Original:    fmt.Sprintf("指针%s未初始化", name),
+				Suggested:   fmt.Sprintf("声明时初始化，如%s = nullptr", name),
+				StartColumn: startCol,
+				EndColumn:   endCol,
+			})
+		}
+		return issues
+	},
+	Fix: fixUninitializedPointer,
+}
+
+var bareExceptRe = regexp.MustCompile(`^\s*(except)\s*:`)
+
+// 规则4：Python 中的裸 except
+var checkBareExcept = StaticChecker{
+	Rule: "规则4",
+	Check: func(file string, lines []string) []Issue {
+		var issues []Issue
+		for i, line := range lines {
+			if m := bareExceptRe.FindStringSubmatchIndex(stripPyLine(line)); m != nil {
+				startCol, endCol := columnRange(line, m[2], m[3])
+				issues = append(issues, Issue{
+					File:        file,
+					Line:        i + 1,
+					Rule:        "规则4",
+					Original:    "裸except会捕获包括KeyboardInterrupt在内的所有异常",
+					Suggested:   "指定具体的异常类型，如except ValueError:",
+					StartColumn: startCol,
+					EndColumn:   endCol,
+				})
+			}
+		}
+		return issues
+	},
+	Fix: fixBareExcept,
+}
+
+var mutableDefaultRe = regexp.MustCompile(`^\s*(?:async\s+)?def\s+\w+\s*\(.*\b(\w+)\s*(?::[^=]+)?=\s*(\[\s*\]|\{\s*\}|list\(\)|dict\(\)|set\(\))`)
+
+// 规则5：Python 函数参数使用可变对象作为默认值
+var checkMutableDefault = StaticChecker{
+	Rule: "规则5",
+	Check: func(file string, lines []string) []Issue {
+		var issues []Issue
+		for i, line := range lines {
+			stripped := stripPyLine(line)
+			if m := mutableDefaultRe.FindStringSubmatchIndex(stripped); m != nil {
+				name, value := stripped[m[2]:m[3]], stripped[m[4]:m[5]]
+				startCol, endCol := columnRange(line, m[2], m[5])
+				issues = append(issues, Issue{
+					File:        file,
+					Line:        i + 1,
+					Rule:        "规则5",
+					Original:    fmt.Sprintf("参数%s使用可变对象%s作为默认值", name, value),
+					Suggested:   fmt.Sprintf("默认值改为None，在函数内部创建%s", value),
+					StartColumn: startCol,
+					EndColumn:   endCol,
+				})
+			}
+		}
+		return issues
+	},
+}
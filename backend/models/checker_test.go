@@ -0,0 +1,103 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 检查器报告问题的行号
+func issueLines(issues []Issue) []int {
+	var lines []int
+	for _, is := range issues {
+		lines = append(lines, is.Line)
+	}
+	return lines
+}
+
+func TestStaticCheckers(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker StaticChecker
+		code    string
+		want    []int
+	}{
+		{
+			name:    "有符号索引",
+			checker: checkSignedIndex,
+			code:    "int i = 0;\nsize_t j = 0;\na[i] = b[j];\nc[ j ] = d[i];",
+			want:    []int{3, 4},
+		},
+		{
+			name:    "注释和字符串中的索引不算",
+			checker: checkSignedIndex,
+			code:    "int i;\n// a[i]\nputs(\"a[i]\");",
+		},
+		{
+			name:    "C风格转换",
+			checker: checkCStyleCast,
+			code:    "double d = (double)sum;\nint *p = (int *)malloc(4);\nf((int)x, 1);\nauto v = static_cast<int>(x);",
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "sizeof 不是类型转换",
+			checker: checkCStyleCast,
+			code:    "int *a = malloc(sizeof (int) * n);\nsize_t s = sizeof(long) * 2;\nint b = sizeof (int) * (int)x;",
+			want:    []int{3},
+		},
+		{
+			name:    "未初始化的局部指针",
+			checker: checkUninitializedPointer,
+			code:    "void f() {\n  int *p;\n  char *q = nullptr;\n  return *p;\n}",
+			want:    []int{2},
+		},
+		{
+			name:    "类成员指针不算",
+			checker: checkUninitializedPointer,
+			code: strings.Join([]string{
+				"class Node : public Base",
+				"{",
+				"  Node *next;",
+				"  void reset() {",
+				"    Node *tmp;",
+				"  }",
+				"};",
+				"struct Pair { int *first;",
+				"  int *second;",
+				"};",
+				"template <class T> T *make() {",
+				"  T *obj;",
+				"}",
+				"Node *head;",
+			}, "\n"),
+			want: []int{5, 12, 14},
+		},
+		{
+			name:    "裸except",
+			checker: checkBareExcept,
+			code:    "try:\n    run()\nexcept:\n    pass\nexcept ValueError:\n    pass\n# except:",
+			want:    []int{3},
+		},
+		{
+			name:    "可变默认值",
+			checker: checkMutableDefault,
+			code:    "def f(a, b=[]):\nasync def g(c: dict = {}):\ndef h(d=None):\ndef k(e=set()):\nx = '(b=[])'",
+			want:    []int{1, 2, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := issueLines(tt.checker.Check("a", strings.Split(tt.code, "\n")))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reported lines %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixCStyleCastSkipsSizeof(t *testing.T) {
+	got := fixCStyleCast("int b = sizeof (int) * (int)x;", nil)
+	if want := "int b = sizeof (int) * static_cast<int>(x);"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
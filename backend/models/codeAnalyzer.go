@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"standardizer/utils"
 	"strings"
 	"sync"
@@ -16,9 +17,50 @@ import (
 
 // 代码分析结构体
 type CodeAnalyzer struct {
-	Llm     llms.Model
-	Rules   []string
+	Llm llms.Model
+	// 默认模型名称，用于记录调用
+	Model string
+	// LLM调用失败时的重试次数
+	MaxRetries int
+	Rules      []Rule
+	// 可供项目选择的规则集，未选择时使用 Rules
+	RuleSets map[string][]Rule
+	// Rules 对应的规则集名称
+	DefaultRuleSet string
+	// 当前扫描项目的配置
+	Project *ProjectConfig
 	Results map[string][]Issue
+	// 各文件中的源码屏蔽注释
+	Suppressions map[string][]Suppression
+	// 是否为问题生成修复，以及是否验证生成的修复
+	AutoFix     bool
+	VerifyFixes bool
+	// 多模型投票
+	Consensus Consensus
+	// 提示词模板库，为空时使用各语言的内置模板
+	Prompts *PromptLibrary
+	// 本次扫描各提示词模板使用的版本，由 PromptLibrary.Assign 分配
+	PromptVersions map[string]string
+	// 规则示例的 token 预算，为 0 时使用默认值，小于 0 时不加入示例
+	ExampleTokens int
+	// 规则分片
+	Sharding Sharding
+	// 按嵌入向量为代码块检索相关规则，为空时使用全部适用规则
+	Retriever *RuleRetriever
+	// 项目索引，用于向提示词加入其他位置的相关声明
+	Index *ProjectIndex
+	// 相关声明的 token 预算，为 0 时使用默认值，小于 0 时不加入
+	ContextTokens int
+	// 单次LLM响应的 token 上限，为 0 时使用默认值，小于 0 时不限制
+	MaxResponseTokens int
+	// 流式解析出问题时的回调，用于推送任务进度
+	OnFinding func(Issue)
+	// 每次LLM调用结束时的回调，用于记录用量
+	OnCall func(*LLMCall)
+	// 本次扫描使用的提示词模板
+	promptsUsed map[string]bool
+	// 已推送的问题
+	emitted map[string]bool
 	Mu      sync.Mutex
 	Ctx     context.Context
 }
@@ -30,15 +72,105 @@ type Issue struct {
 	Rule      string
 	Original  string
 	Suggested string
+	Fix       *Fix
+	// 得票数与得票比例，静态检查的结果置信度为 1
+	Votes      int
+	Confidence float64
+	// 规则目录中的严重级别
+	Severity string
+	// 问题所在的列范围，从 1 开始，包含结束列
+	StartColumn int
+	EndColumn   int
+	// 问题行的源码
+	Snippet string
+	// 发现问题的引擎
+	Engine string
+	// LLM 发现的问题所用的提示词模板
+	Prompt string
+}
+
+// 检查引擎
+const (
+	EngineStatic = "static"
+	EngineLLM    = "llm"
+)
+
+// ProcessFileOrDirectory 处理文件或目录的逻辑
+func (c *CodeAnalyzer) ProcessFileOrDirectory(filePath string) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		slog.Error("获取文件信息失败", "error", err)
+		return err
+	}
+
+	if fileInfo.IsDir() {
+		// 读取项目根目录下的配置文件
+		project, err := LoadProjectConfig(filePath)
+		if err != nil {
+			return err
+		}
+		if err := c.UseProject(project); err != nil {
+			return err
+		}
+
+		// 收集目录中的文件，被排除的文件仍加入项目索引以提供声明
+		var sources, files []string
+		err = filepath.Walk(filePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != filePath && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			sources = append(sources, path)
+			if !project.Accepts(path, DetectLanguage(path)) {
+				slog.Debug("按项目配置跳过文件", "file", path)
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("遍历目录失败: %w", err)
+		}
+
+		// 建立项目索引后逐个处理文件
+		c.Index = BuildProjectIndex(filePath, sources)
+		for _, path := range files {
+			if err := c.ProcessFile(path); err != nil {
+				return fmt.Errorf("处理文件失败: %w", err)
+			}
+		}
+	} else {
+		// 处理单个文件，使用默认配置
+		project := DefaultProjectConfig()
+		if err := c.UseProject(project); err != nil {
+			return err
+		}
+		if !project.Accepts(filePath, DetectLanguage(filePath)) {
+			slog.Debug("跳过不支持的文件类型", "file", filePath)
+			return nil
+		}
+		// 单个文件只索引其自身及包含的头文件
+		c.Index = BuildProjectIndex(filepath.Dir(filePath), []string{filePath})
+		if err := c.ProcessFile(filePath); err != nil {
+			return fmt.Errorf("处理文件失败: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // 处理单个文件
 func (c *CodeAnalyzer) ProcessFile(path string) error {
 	slog.Info("开始处理文件", "file", path)
 
-	// 只处理C++文件
-	if !strings.HasSuffix(path, ".cpp") && !strings.HasSuffix(path, ".h") && !strings.HasSuffix(path, ".hpp") {
-		slog.Debug("跳过非C++文件", "file", path)
+	lang := DetectLanguage(path)
+	if lang == nil {
+		slog.Debug("跳过无法识别语言的文件", "file", path)
 		return nil
 	}
 
@@ -48,35 +180,119 @@ func (c *CodeAnalyzer) ProcessFile(path string) error {
 		return err
 	}
 
+	// 解析源码中的屏蔽注释，整个文件屏蔽所有规则时无需分析
+	lines := strings.Split(string(content), "\n")
+	suppressions := ParseSuppressions(path, lines)
+	for _, s := range suppressions {
+		if s.Scope == SuppressFile && s.Rules == "" {
+			slog.Info("文件已通过注释屏蔽，跳过分析", "file", path, "reason", s.Reason)
+			c.Mu.Lock()
+			c.Results[path] = nil
+			c.Suppressions[path] = suppressions
+			c.Mu.Unlock()
+			return nil
+		}
+	}
+
+	rules := c.rulesFor(path, lang)
+	if len(rules) == 0 {
+		slog.Info("没有适用于该文件的规则，跳过分析", "file", path, "language", lang.Name)
+		c.Mu.Lock()
+		c.Results[path] = nil
+		c.Mu.Unlock()
+		return nil
+	}
+
+	// 静态检查
+	static := RunStaticCheckers(lang, path, lines, rules)
+	slog.Debug("静态检查完成", "file", path, "issue_count", len(static))
+
 	// 分块处理大文件
-	chunks := utils.SplitCodeIntoChunks(string(content), 500) // 500行/块
-	slog.Debug("文件分块完成", "file", path, "chunk_count", len(chunks))
+	chunks := lang.Chunk(string(content))
+	slog.Debug("文件分块完成", "file", path, "language", lang.Name, "chunk_count", len(chunks))
+
+	for _, chunk := range chunks {
+		c.analyzeCodeChunk(path, chunk.Code, chunk.StartLine, rules, lang)
+	}
+
+	// 合并静态检查结果，同一行同一规则只保留一条
+	c.Mu.Lock()
+	c.Results[path] = annotateIssues(mergeIssues(static, c.Results[path]), lines, rules)
+	c.Mu.Unlock()
+
+	// 过滤源码中屏蔽注释命中的问题
+	if len(suppressions) > 0 {
+		c.Mu.Lock()
+		c.Results[path] = ApplySuppressions(c.Results[path], suppressions)
+		c.Suppressions[path] = suppressions
+		c.Mu.Unlock()
+		slog.Debug("源码屏蔽注释已应用", "file", path, "suppression_count", len(suppressions))
+	}
 
-	for i, chunk := range chunks {
-		startLine := i * 500
-		c.analyzeCodeChunk(path, chunk, startLine)
+	if c.AutoFix {
+		c.generateFixes(path, lines, lang, rules)
+		if c.VerifyFixes {
+			c.verifyFixes(path, lines, lang, rules)
+		}
 	}
 
 	slog.Info("文件处理完成", "file", path)
 	return nil
 }
 
-// 分析代码块
-func (c *CodeAnalyzer) analyzeCodeChunk(filePath, code string, startLine int) {
+// 分析代码块，启用规则分片时每个分片单独请求
+func (c *CodeAnalyzer) analyzeCodeChunk(filePath, code string, startLine int, rules []Rule, lang *Language) {
 	slog.Info("开始分析代码块", "file", filePath, "start_line", startLine)
-	// 构造LLM提示
-	prompt := c.buildPrompt(code)
+	rules = c.retrieveRules(filePath, startLine, code, rules)
+	shards := c.ruleShards(code, rules)
+	if len(shards) == 0 {
+		slog.Debug("代码块不涉及任何规则，跳过分析", "file", filePath, "start_line", startLine)
+		return
+	}
+	context := c.projectContext(filePath, startLine, code)
+	for _, shard := range shards {
+		c.analyzeShard(filePath, code, context, startLine, shard, lang)
+	}
+}
 
-	// 调用LLM
-	response, err := c.Llm.Call(c.Ctx, prompt)
+// 检索与代码块最相关的规则，检索失败时使用全部规则
+func (c *CodeAnalyzer) retrieveRules(filePath string, startLine int, code string, rules []Rule) []Rule {
+	if c.Retriever == nil {
+		return rules
+	}
+	selected, err := c.Retriever.Retrieve(c.Ctx, code, rules)
 	if err != nil {
-		slog.Error("LLM分析失败", "file", filePath, "error", err)
-		return
+		slog.Warn("检索相关规则失败，使用全部规则", "file", filePath, "start_line", startLine, "error", err)
+		return rules
+	}
+	slog.Debug("检索相关规则完成", "file", filePath, "start_line", startLine, "rule_count", len(selected))
+	return selected
+}
+
+// 代码块引用的其他位置的声明
+func (c *CodeAnalyzer) projectContext(filePath string, startLine int, code string) string {
+	budget := c.ContextTokens
+	if budget == 0 {
+		budget = defaultContextTokens
 	}
-	slog.Debug("LLM分析成功", "file", filePath)
+	return c.Index.Context(filePath, startLine, code, budget)
+}
 
-	// 解析响应
-	issues := parseLLMResponse(response, filePath, startLine)
+// 用一个规则分片分析代码块
+func (c *CodeAnalyzer) analyzeShard(filePath, code, context string, startLine int, rules []Rule, lang *Language) {
+	// 构造LLM提示
+	prompt, promptRef := c.buildPrompt(code, context, rules, lang)
+
+	// 调用各模型并按投票合并结果
+	ballots := c.collectBallots(filePath, prompt, startLine, rules)
+	if len(ballots) == 0 {
+		return
+	}
+	slog.Debug("LLM分析成功", "file", filePath, "ballot_count", len(ballots), "rule_count", len(rules))
+	issues := mergeBallots(ballots, c.Consensus.Agreement)
+	for i := range issues {
+		issues[i].Prompt = promptRef
+	}
 
 	// 存储结果
 	c.Mu.Lock()
@@ -85,22 +301,148 @@ func (c *CodeAnalyzer) analyzeCodeChunk(filePath, code string, startLine int) {
 	slog.Debug("结果存储完成", "file", filePath, "issue_count", len(issues))
 }
 
-// 构建LLM提示
-func (c *CodeAnalyzer) buildPrompt(code string) string {
-	rulesStr := strings.Join(c.Rules, "\n")
+// 构建LLM分析提示，context 为代码块引用的其他位置的声明，并记录本次扫描使用的模板版本
+func (c *CodeAnalyzer) buildPrompt(code, context string, rules []Rule, lang *Language) (string, string) {
+	prompt, tmpl := c.renderPrompt(code, context, rules, lang)
+	c.Mu.Lock()
+	if c.promptsUsed == nil {
+		c.promptsUsed = make(map[string]bool)
+	}
+	c.promptsUsed[tmpl.Ref()] = true
+	c.Mu.Unlock()
+	return prompt, tmpl.Ref()
+}
+
+// 渲染LLM提示，不记录模板使用情况
+func (c *CodeAnalyzer) renderPrompt(code, context string, rules []Rule, lang *Language) (string, *PromptTemplate) {
+	ruleLines := make([]string, 0, len(rules))
+	for _, r := range rules {
+		ruleLines = append(ruleLines, r.String())
+	}
+	budget := c.ExampleTokens
+	if budget == 0 {
+		budget = defaultExampleTokens
+	}
+	examples := selectExamples(rules, lang, code, budget)
+	data := PromptData{
+		Language:     lang.DisplayName,
+		Rules:        rules,
+		RulesText:    strings.Join(ruleLines, "\n"),
+		Examples:     examples,
+		ExamplesText: formatExamples(examples),
+		Context:      context,
+		Code:         code,
+	}
 
-	return fmt.Sprintf(`你是一个C++专家，正在检查代码是否符合代码规范。请遵循以下规则：
-%s
+	tmpl := c.Prompts.Select(c.RuleSetName(), lang.Name, c.PromptVersions)
+	if tmpl == nil {
+		tmpl = lang.prompt
+	}
+	prompt, err := tmpl.Render(data)
+	if err != nil {
+		// 模板库中的模板渲染失败时退回内置模板
+		slog.Error("渲染提示词失败，使用内置模板", "template", tmpl.Ref(), "error", err)
+		tmpl = lang.prompt
+		prompt, _ = tmpl.Render(data)
+	}
+	return prompt, tmpl
+}
 
-请分析以下C++代码片段：
+// 使用项目配置，配置中的规则集必须存在
+func (c *CodeAnalyzer) UseProject(project *ProjectConfig) error {
+	if project != nil && project.RuleSet != "" {
+		if _, ok := c.RuleSets[project.RuleSet]; !ok {
+			return fmt.Errorf("规则集 %s 不存在", project.RuleSet)
+		}
+	}
+	c.Project = project
+	return nil
+}
 
-输出格式要求：
-1. 按行分析，每行格式：[行号]:[规则编号]:[问题描述]:[建议修正]
-2. 如果没有问题，输出"共检查xx行代码，没有问题"
-3. 示例：
-   42:规则2:危险的类型转换:使用static_cast<int>(value)代替(int)value
+// 本次扫描使用的规则集名称
+func (c *CodeAnalyzer) RuleSetName() string {
+	if c.Project != nil && c.Project.RuleSet != "" {
+		return c.Project.RuleSet
+	}
+	return c.DefaultRuleSet
+}
+
+// 本次扫描使用的规则，已应用项目配置中的严重级别覆盖
+func (c *CodeAnalyzer) ActiveRules() []Rule {
+	if c.Project == nil {
+		return c.Rules
+	}
+	rules := c.Rules
+	if set, ok := c.RuleSets[c.Project.RuleSet]; ok {
+		rules = set
+	}
+	return c.Project.ApplySeverity(rules)
+}
+
+// 对该文件生效的规则：适用于文件语言且未被项目配置禁用
+func (c *CodeAnalyzer) rulesFor(path string, lang *Language) []Rule {
+	var rules []Rule
+	for _, r := range c.ActiveRules() {
+		if r.AppliesTo(lang.Name) {
+			rules = append(rules, r)
+		}
+	}
+	if c.Project == nil {
+		return rules
+	}
+	return c.Project.RulesFor(path, rules)
+}
 
-请开始分析：%s`, rulesStr, code)
+// 只保留给定规则的问题，模型报告的未启用或不存在的规则会被丢弃
+func keepRules(issues []Issue, rules []Rule) []Issue {
+	enabled := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		enabled[r.ID] = true
+	}
+	kept := issues[:0]
+	for _, issue := range issues {
+		if enabled[issue.Rule] {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// 合并静态检查与 LLM 的结果，同一行同一规则以静态检查为准
+func mergeIssues(static, llm []Issue) []Issue {
+	type key struct {
+		line int
+		rule string
+	}
+	seen := make(map[key]bool, len(static))
+	merged := make([]Issue, 0, len(static)+len(llm))
+	for _, issue := range static {
+		issue.Votes, issue.Confidence = 1, 1
+		seen[key{issue.Line, issue.Rule}] = true
+		merged = append(merged, issue)
+	}
+	for _, issue := range llm {
+		if !seen[key{issue.Line, issue.Rule}] {
+			seen[key{issue.Line, issue.Rule}] = true
+			merged = append(merged, issue)
+		}
+	}
+	return merged
+}
+
+// 补充严重级别、源码片段和列范围，未给出列范围的问题取整行代码
+func annotateIssues(issues []Issue, lines []string, rules []Rule) []Issue {
+	for i := range issues {
+		issue := &issues[i]
+		issue.Severity = RuleSeverity(rules, issue.Rule)
+		line := sourceLine(lines, issue.Line)
+		issue.Snippet = strings.TrimSpace(line)
+		if issue.StartColumn == 0 && issue.Snippet != "" {
+			start := strings.Index(line, issue.Snippet)
+			issue.StartColumn, issue.EndColumn = columnRange(line, start, start+len(issue.Snippet))
+		}
+	}
+	return issues
 }
 
 // 提取文件名并去掉扩展名
@@ -111,40 +453,90 @@ func extractFileName(path string) string {
 }
 
 // 生成报告
-func (c *CodeAnalyzer) GenerateReport(path string) map[string]interface{} {
+func (c *CodeAnalyzer) GenerateReport(path string) *Report {
 	slog.Info("开始生成报告")
 
-	fileName := extractFileName(path)
-
-	report := make(map[string]interface{})
-	report["file-name"] = fileName
-	report["title"] = "代码规范检查报告"
-	report["rule_count"] = len(c.Rules)
+	rules := c.ActiveRules()
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		FileName:      extractFileName(path),
+		Title:         "代码规范检查报告",
+		RuleSet:       c.RuleSetName(),
+		RuleCount:     len(rules),
+		Rules:         rules,
+	}
 
-	var issues []map[string]interface{}
-	totalIssues := 0
 	for file, fileIssues := range c.Results {
+		lines := readSourceLines(file)
 		for _, issue := range fileIssues {
-			issueData := map[string]interface{}{
-				"file":      file,
-				"line":      issue.Line,
-				"rule":      issue.Rule,
-				"original":  issue.Original,
-				"suggested": issue.Suggested,
+			severity := issue.Severity
+			if severity == "" {
+				severity = RuleSeverity(rules, issue.Rule)
 			}
-			issues = append(issues, issueData)
-			totalIssues++
+			report.Findings = append(report.Findings, Finding{
+				File:        file,
+				Line:        issue.Line,
+				Rule:        issue.Rule,
+				Severity:    severity,
+				Rank:        SeverityRank(severity),
+				Status:      FindingOpen,
+				Original:    issue.Original,
+				Suggested:   issue.Suggested,
+				Fingerprint: Fingerprint(file, issue.Rule, sourceLine(lines, issue.Line)),
+				Fix:         issue.Fix,
+				Votes:       issue.Votes,
+				Confidence:  issue.Confidence,
+				StartColumn: issue.StartColumn,
+				EndColumn:   issue.EndColumn,
+				Snippet:     issue.Snippet,
+				Engine:      issue.Engine,
+				Prompt:      issue.Prompt,
+			})
 		}
+		report.Files = append(report.Files, FileResult{File: file})
+	}
+	for ref := range c.promptsUsed {
+		report.Prompts = append(report.Prompts, ref)
 	}
+	sort.Strings(report.Prompts)
+	for _, suppressions := range c.Suppressions {
+		report.Suppressions = append(report.Suppressions, suppressions...)
+	}
+	SortFindings(report.Findings)
+	sort.Slice(report.Suppressions, func(i, j int) bool {
+		if report.Suppressions[i].File != report.Suppressions[j].File {
+			return report.Suppressions[i].File < report.Suppressions[j].File
+		}
+		return report.Suppressions[i].Comment < report.Suppressions[j].Comment
+	})
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].File < report.Files[j].File
+	})
 
-	report["total_files"] = len(c.Results)
-	report["total_issues"] = totalIssues
-	report["issues"] = issues
+	report.Recount()
 
-	slog.Info("报告生成完成", "total_files", report["total_files"], "total_issues", report["total_issues"])
+	slog.Info("报告生成完成", "total_files", report.TotalFiles, "total_issues", report.TotalIssues)
 	return report
 }
 
+// 按行读取源文件，读取失败时返回空
+func readSourceLines(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("读取源文件失败", "file", path, "error", err)
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// 取第 line 行源码（从 1 开始）
+func sourceLine(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
 // 解析LLM响应
 func parseLLMResponse(response, filePath string, startLine int) []Issue {
 	slog.Debug("开始解析LLM响应", "file", filePath)
@@ -170,12 +562,45 @@ func parseLLMResponse(response, filePath string, startLine int) []Issue {
 			Rule:      "规则" + matches[2],
 			Original:  matches[3],
 			Suggested: matches[4],
+			Engine:    EngineLLM,
 		})
 	}
 	slog.Debug("LLM响应解析完成", "file", filePath, "issue_count", len(issues))
 	return issues
 }
 
+// 以当前分析器的配置创建一次扫描专用的分析器。扫描结果、项目配置和回调等状态
+// 都保存在分析器上，并发的扫描须各自使用 NewScan 创建的分析器
+func (c *CodeAnalyzer) NewScan() *CodeAnalyzer {
+	return &CodeAnalyzer{
+		Llm:               c.Llm,
+		Model:             c.Model,
+		MaxRetries:        c.MaxRetries,
+		Rules:             c.Rules,
+		RuleSets:          c.RuleSets,
+		DefaultRuleSet:    c.DefaultRuleSet,
+		Results:           make(map[string][]Issue),
+		Suppressions:      make(map[string][]Suppression),
+		AutoFix:           c.AutoFix,
+		VerifyFixes:       c.VerifyFixes,
+		Consensus:         c.Consensus,
+		Prompts:           c.Prompts,
+		ExampleTokens:     c.ExampleTokens,
+		Sharding:          c.Sharding,
+		Retriever:         c.Retriever,
+		ContextTokens:     c.ContextTokens,
+		MaxResponseTokens: c.MaxResponseTokens,
+		Ctx:               c.Ctx,
+	}
+}
+
 func (c *CodeAnalyzer) ClearAnalyzerResults() {
 	c.Results = make(map[string][]Issue)
+	c.Suppressions = make(map[string][]Suppression)
+	c.Project = nil
+	c.Index = nil
+	c.PromptVersions = nil
+	c.OnFinding = nil
+	c.promptsUsed = nil
+	c.emitted = nil
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewScanDoesNotShareState(t *testing.T) {
+	base := &CodeAnalyzer{
+		Llm:            &scriptedModel{responses: []string{""}},
+		MaxRetries:     2,
+		Rules:          []Rule{{ID: "规则1"}},
+		DefaultRuleSet: "gjb",
+		Ctx:            context.Background(),
+	}
+	a, b := base.NewScan(), base.NewScan()
+	a.OnFinding = func(Issue) {}
+	a.Results["a.cpp"] = []Issue{{File: "a.cpp", Line: 1}}
+	if err := a.UseProject(&ProjectConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	a.emitFinding(Issue{File: "a.cpp", Line: 1, Rule: "规则1"})
+
+	if len(b.Results) != 0 || b.Project != nil || b.OnFinding != nil || b.emitted != nil {
+		t.Errorf("second scan sees state of the first: results=%v project=%v", b.Results, b.Project)
+	}
+	if b.Llm != base.Llm || b.MaxRetries != 2 || b.RuleSetName() != "gjb" || len(b.Rules) != 1 {
+		t.Errorf("scan analyzer lost configuration: %+v", b)
+	}
+}
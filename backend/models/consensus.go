@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 多次采样同一模型时使用的温度，保证各次结果有差异
+const sampleTemperature = 0.7
+
+// 参与投票的模型
+type Voter struct {
+	Name  string
+	Model llms.Model
+}
+
+// 多模型投票配置：每个代码块交给每个模型分析 Samples 次，
+// 同一行同一规则的问题得票比例不低于 Agreement 时才保留
+type Consensus struct {
+	Voters    []Voter // 为空时只使用 CodeAnalyzer.Llm
+	Samples   int     // 每个模型的采样次数，默认 1
+	Agreement float64 // 0 到 1，默认 0.5
+}
+
+// 各模型对代码块的分析结果，每个元素为一次成功调用解析出的问题。
+// 提前终止的调用只有解析出问题时才计入，空的部分回答不能算作“没有问题”的一票
+func (c *CodeAnalyzer) collectBallots(filePath, prompt string, startLine int, rules []Rule) [][]Issue {
+	voters := c.Consensus.Voters
+	if len(voters) == 0 {
+		voters = []Voter{c.defaultVoter()}
+	}
+	samples := max(c.Consensus.Samples, 1)
+
+	var ballots [][]Issue
+	for _, voter := range voters {
+		for i := 0; i < samples; i++ {
+			var options []llms.CallOption
+			if samples > 1 {
+				options = append(options, llms.WithTemperature(sampleTemperature))
+			}
+			response, err := c.streamCall(voter, prompt, filePath, startLine, rules, options...)
+			if err != nil && !errors.Is(err, ErrAborted) {
+				slog.Error("LLM分析失败", "file", filePath, "model", voter.Name, "error", err)
+				continue
+			}
+			issues := keepRules(parseLLMResponse(response, filePath, startLine), rules)
+			if err != nil && len(issues) == 0 {
+				slog.Warn("提前终止的分析没有结果，不计入投票", "file", filePath, "model", voter.Name)
+				continue
+			}
+			ballots = append(ballots, issues)
+		}
+	}
+	return ballots
+}
+
+// 按行号和规则合并多次分析的结果，保留得票达到阈值的问题，得票比例记为置信度
+func mergeBallots(ballots [][]Issue, agreement float64) []Issue {
+	if len(ballots) == 0 {
+		return nil
+	}
+	if agreement <= 0 {
+		agreement = 0.5
+	}
+	minVotes := max(int(math.Ceil(agreement*float64(len(ballots))-1e-9)), 1)
+
+	type key struct {
+		line int
+		rule string
+	}
+	votes := make(map[key]int)
+	first := make(map[key]Issue)
+	for _, ballot := range ballots {
+		// 同一次分析中重复报告的问题只计一票
+		voted := make(map[key]bool)
+		for _, issue := range ballot {
+			k := key{issue.Line, issue.Rule}
+			if voted[k] {
+				continue
+			}
+			voted[k] = true
+			votes[k]++
+			if _, ok := first[k]; !ok {
+				first[k] = issue
+			}
+		}
+	}
+
+	var merged []Issue
+	for k, issue := range first {
+		if votes[k] < minVotes {
+			continue
+		}
+		issue.Votes = votes[k]
+		issue.Confidence = float64(votes[k]) / float64(len(ballots))
+		merged = append(merged, issue)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Line != merged[j].Line {
+			return merged[i].Line < merged[j].Line
+		}
+		return merged[i].Rule < merged[j].Rule
+	})
+	return merged
+}
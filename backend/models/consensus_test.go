@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 按顺序返回预设回答的模型，流式调用时逐行推送
+type scriptedModel struct {
+	mu        sync.Mutex
+	responses []string
+	calls     int
+}
+
+func (m *scriptedModel) GenerateContent(ctx context.Context, _ []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.mu.Lock()
+	response := m.responses[m.calls%len(m.responses)]
+	m.calls++
+	m.mu.Unlock()
+
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, line := range strings.SplitAfter(response, "\n") {
+			if err := opts.StreamingFunc(ctx, []byte(line)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: response}}}, nil
+}
+
+func (m *scriptedModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// 思考过程中陷入循环的回答
+var thinkingLoop = "<think>\n" + strings.Repeat("让我再检查一下这一行代码。\n", 10)
+
+func TestCollectBallotsSkipsEmptyAbortedBallot(t *testing.T) {
+	rules := []Rule{{ID: "规则3", Description: "所有指针必须初始化"}}
+	c := &CodeAnalyzer{
+		Llm:       &scriptedModel{responses: []string{"2:规则3:int *p;:int *p = nullptr;\n", thinkingLoop}},
+		Consensus: Consensus{Samples: 2, Agreement: 0.6},
+		Ctx:       context.Background(),
+	}
+
+	ballots := c.collectBallots("a.cpp", "prompt", 0, rules)
+	if len(ballots) != 1 {
+		t.Fatalf("got %d ballots, want 1（中止且没有结果的一次不计入）", len(ballots))
+	}
+	issues := mergeBallots(ballots, c.Consensus.Agreement)
+	if len(issues) != 1 || issues[0].Line != 2 || issues[0].Confidence != 1 {
+		t.Fatalf("merged = %+v, want 第 2 行问题且置信度为 1", issues)
+	}
+}
+
+func TestCollectBallotsKeepsAbortedBallotWithFindings(t *testing.T) {
+	rules := []Rule{{ID: "规则3", Description: "所有指针必须初始化"}}
+	// 报告问题后输出开始循环
+	looping := "2:规则3:int *p;:int *p = nullptr;\n" + strings.Repeat("5:规则3:int *q;:int *q = nullptr;\n", 10)
+	c := &CodeAnalyzer{
+		Llm: &scriptedModel{responses: []string{looping}},
+		Ctx: context.Background(),
+	}
+
+	ballots := c.collectBallots("a.cpp", "prompt", 0, rules)
+	if len(ballots) != 1 {
+		t.Fatalf("got %d ballots, want 1", len(ballots))
+	}
+	if len(ballots[0]) == 0 || ballots[0][0].Line != 2 {
+		t.Fatalf("ballot = %+v, want 包含第 2 行问题", ballots[0])
+	}
+}
+
+func TestStreamCallReturnsErrAborted(t *testing.T) {
+	c := &CodeAnalyzer{Llm: &scriptedModel{responses: []string{thinkingLoop}}, Ctx: context.Background()}
+	answer, err := c.streamCall(c.defaultVoter(), "prompt", "a.cpp", 0, nil)
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("err = %v, want ErrAborted", err)
+	}
+	if answer != "" {
+		t.Errorf("answer = %q, want 空（思考过程不计入回答）", answer)
+	}
+}
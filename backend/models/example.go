@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 未配置时 few-shot 示例占用的 token 预算
+const defaultExampleTokens = 600
+
+// 提示词中的一个规则示例
+type PromptExample struct {
+	Rule string
+	RuleExample
+}
+
+// 粗略估算 token 数：ASCII 按 4 个字符一个 token，其他字符各算一个
+func estimateTokens(s string) int {
+	ascii := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < utf8.RuneSelf {
+			ascii++
+		}
+	}
+	return (ascii+3)/4 + utf8.RuneCountInString(s) - ascii
+}
+
+var codeTokenRe = regexp.MustCompile(`[A-Za-z_]\w*|[^\s\w]`)
+
+// 代码中出现的标识符、关键字和符号
+func codeTokens(code string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, t := range codeTokenRe.FindAllString(code, -1) {
+		tokens[t] = true
+	}
+	return tokens
+}
+
+// 示例与代码块的相关度：违规示例中出现在代码块里的记号比例
+func exampleRelevance(example RuleExample, chunkTokens map[string]bool) float64 {
+	tokens := codeTokens(example.NonCompliant)
+	if len(tokens) == 0 {
+		return 0
+	}
+	hits := 0
+	for t := range tokens {
+		if chunkTokens[t] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(tokens))
+}
+
+// 在 token 预算内为代码块选择最相关的示例：先为每条规则选一个最相关的示例，
+// 预算有剩余时再补充其他示例
+func selectExamples(rules []Rule, lang *Language, code string, budget int) []PromptExample {
+	if budget <= 0 {
+		return nil
+	}
+	type candidate struct {
+		example   PromptExample
+		relevance float64
+		tokens    int
+		first     bool // 该规则相关度最高的示例
+	}
+	chunkTokens := codeTokens(code)
+	var candidates []candidate
+	for _, r := range rules {
+		best := -1
+		for _, e := range r.Examples {
+			if e.Language != "" && e.Language != lang.Name {
+				continue
+			}
+			c := candidate{
+				example:   PromptExample{Rule: r.ID, RuleExample: e},
+				relevance: exampleRelevance(e, chunkTokens),
+				tokens:    estimateTokens(e.NonCompliant + e.Compliant),
+			}
+			candidates = append(candidates, c)
+			if best < 0 || c.relevance > candidates[best].relevance {
+				best = len(candidates) - 1
+			}
+		}
+		if best >= 0 {
+			candidates[best].first = true
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].first != candidates[j].first {
+			return candidates[i].first
+		}
+		return candidates[i].relevance > candidates[j].relevance
+	})
+
+	var selected []PromptExample
+	for _, c := range candidates {
+		if c.tokens > budget {
+			continue
+		}
+		budget -= c.tokens
+		selected = append(selected, c.example)
+	}
+	// 按规则顺序排列，便于模型对照
+	order := make(map[string]int, len(rules))
+	for i, r := range rules {
+		order[r.ID] = i
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return order[selected[i].Rule] < order[selected[j].Rule]
+	})
+	return selected
+}
+
+// 提示词中的示例文本
+func formatExamples(examples []PromptExample) string {
+	var b strings.Builder
+	for _, e := range examples {
+		fmt.Fprintf(&b, "%s 违规示例：\n%s\n%s 合规示例：\n%s\n", e.Rule, strings.TrimRight(e.NonCompliant, "\n"), e.Rule, strings.TrimRight(e.Compliant, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
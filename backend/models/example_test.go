@@ -0,0 +1,67 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "abcd", want: 1},
+		{s: "abcde", want: 2},
+		{s: "中文", want: 2},
+		{s: "int 变量", want: 3},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.s); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSelectExamples(t *testing.T) {
+	pointer := RuleExample{NonCompliant: "int *p;", Compliant: "int *p = nullptr;"}
+	pointerChar := RuleExample{NonCompliant: "char *q;", Compliant: "char *q = nullptr;"}
+	gotoEnd := RuleExample{NonCompliant: "goto end;", Compliant: "return;"}
+	rules := []Rule{
+		{ID: "规则1", Examples: []RuleExample{pointerChar, pointer}},
+		{ID: "规则2", Examples: []RuleExample{gotoEnd, {Language: "python", NonCompliant: "except:", Compliant: "except ValueError:"}}},
+	}
+	size := func(e RuleExample) int { return estimateTokens(e.NonCompliant + e.Compliant) }
+	code := "int *p;\na[i] = (int)x;"
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []string
+	}{
+		{name: "没有预算", budget: 0},
+		// 先为每条规则选最相关的示例，预算恰好用完
+		{name: "预算恰好容纳每条规则一个示例", budget: size(pointer) + size(gotoEnd), want: []string{"规则1 int *p;", "规则2 goto end;"}},
+		{name: "预算差一", budget: size(pointer) + size(gotoEnd) - 1, want: []string{"规则1 int *p;"}},
+		// 放不下的示例跳过，继续尝试后面较小的示例
+		{name: "最相关的示例超出预算", budget: size(gotoEnd), want: []string{"规则2 goto end;"}},
+		// 预算充足时补充其他示例，按规则顺序排列；其他语言的示例不选
+		{name: "预算充足", budget: 1000, want: []string{"规则1 int *p;", "规则1 char *q;", "规则2 goto end;"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			used := 0
+			for _, e := range selectExamples(rules, LanguageByName("cpp"), code, tt.budget) {
+				got = append(got, e.Rule+" "+e.NonCompliant)
+				used += size(e.RuleExample)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+			if used > tt.budget {
+				t.Errorf("selected examples use %d tokens, budget %d", used, tt.budget)
+			}
+		})
+	}
+}
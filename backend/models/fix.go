@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 修复来源
+const (
+	FixFromStatic = "static"
+	FixFromLLM    = "llm"
+)
+
+// 针对一条检查发现的修复：用 Replacement 替换第 StartLine 到 EndLine 行（含）
+type Fix struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	FindingID   uint   `json:"finding_id" gorm:"index"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	Original    string `json:"original" gorm:"type:text"` // 生成修复时被替换的原始代码，用于校验
+	Replacement string `json:"replacement" gorm:"type:text"`
+	Source      string `json:"source" gorm:"size:16"`
+	Applicable  bool   `json:"applicable"`                       // 能否干净地应用到源文件
+	Error       string `json:"error,omitempty" gorm:"type:text"` // 无法应用的原因
+
+	// 验证结果：修复后问题消失且没有引入新问题
+	Verified   bool   `json:"verified"`
+	VerifyNote string `json:"verify_note,omitempty" gorm:"type:text"` // 未通过验证的原因
+}
+
+// 报告中展示的修复状态
+func FixStatusLabel(f *Fix) string {
+	switch {
+	case f == nil:
+		return ""
+	case !f.Applicable:
+		return "不可应用"
+	case f.Verified:
+		return "已验证"
+	default:
+		return "未验证"
+	}
+}
+
+// 原始代码与替换代码的行
+func (f *Fix) originalLines() []string {
+	return strings.Split(f.Original, "\n")
+}
+
+func (f *Fix) ReplacementLines() []string {
+	return strings.Split(f.Replacement, "\n")
+}
+
+// 根据源文件构造修复，range 超出文件时返回 nil
+func NewFix(lines []string, startLine, endLine int, replacement, source string) *Fix {
+	if startLine < 1 || endLine < startLine || endLine > len(lines) {
+		return nil
+	}
+	return &Fix{
+		StartLine:   startLine,
+		EndLine:     endLine,
+		Original:    strings.Join(lines[startLine-1:endLine], "\n"),
+		Replacement: strings.TrimRight(replacement, "\n"),
+		Source:      source,
+	}
+}
+
+// 校验修复能否应用到源文件：原始代码未变化、替换有实际改动
+func (f *Fix) Validate(lines []string) error {
+	if f.StartLine < 1 || f.EndLine < f.StartLine || f.EndLine > len(lines) {
+		return fmt.Errorf("修复范围 %d-%d 超出文件", f.StartLine, f.EndLine)
+	}
+	if strings.Join(lines[f.StartLine-1:f.EndLine], "\n") != f.Original {
+		return fmt.Errorf("源文件第 %d-%d 行已变化", f.StartLine, f.EndLine)
+	}
+	if strings.TrimSpace(f.Replacement) == "" {
+		return fmt.Errorf("修复内容为空")
+	}
+	if f.Replacement == f.Original {
+		return fmt.Errorf("修复内容与原始代码相同")
+	}
+	return nil
+}
+
+// 校验同一文件的一组修复，重叠的修复只保留先出现的一个
+func ValidateFixes(lines []string, fixes []*Fix) {
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return fixes[i].StartLine < fixes[j].StartLine
+	})
+	lastEnd := 0
+	for _, fix := range fixes {
+		if err := fix.Validate(lines); err != nil {
+			fix.Applicable, fix.Error = false, err.Error()
+			continue
+		}
+		if fix.StartLine <= lastEnd {
+			fix.Applicable, fix.Error = false, fmt.Sprintf("与第 %d 行之前的修复重叠", lastEnd)
+			continue
+		}
+		fix.Applicable, fix.Error = true, ""
+		lastEnd = fix.EndLine
+	}
+}
+
+// 将可应用的修复应用到源文件，返回修复后的行和实际应用的修复
+func ApplyFixes(lines []string, fixes []*Fix) ([]string, []*Fix) {
+	ValidateFixes(lines, fixes)
+	var applied []*Fix
+	var result []string
+	next := 1
+	for _, fix := range fixes {
+		if !fix.Applicable {
+			continue
+		}
+		result = append(result, lines[next-1:fix.StartLine-1]...)
+		result = append(result, fix.ReplacementLines()...)
+		next = fix.EndLine + 1
+		applied = append(applied, fix)
+	}
+	result = append(result, lines[next-1:]...)
+	return result, applied
+}
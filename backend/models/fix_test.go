@@ -0,0 +1,85 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 用 replacement 替换 start 到 end 行
+type fixRange struct {
+	start, end  int
+	replacement string
+}
+
+func TestApplyFixes(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name       string
+		fixes      []fixRange
+		want       string
+		applicable []bool
+		errors     []string
+	}{
+		{
+			name:       "多个不重叠的修复",
+			fixes:      []fixRange{{4, 4, "D"}, {1, 2, "AB"}},
+			want:       "AB c D e",
+			applicable: []bool{true, true},
+		},
+		{
+			name:       "重叠的修复只保留先出现的一个",
+			fixes:      []fixRange{{2, 3, "BC"}, {3, 4, "CD"}},
+			want:       "a BC d e",
+			applicable: []bool{true, false},
+			errors:     []string{"", "与第 3 行之前的修复重叠"},
+		},
+		{
+			name:       "最后一行",
+			fixes:      []fixRange{{5, 5, "E1\nE2"}},
+			want:       "a b c d E1 E2",
+			applicable: []bool{true},
+		},
+		{
+			name:       "内容未变化的修复不可应用",
+			fixes:      []fixRange{{1, 1, "a"}},
+			want:       "a b c d e",
+			applicable: []bool{false},
+			errors:     []string{"修复内容与原始代码相同"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fixes []*Fix
+			for _, f := range tt.fixes {
+				fixes = append(fixes, NewFix(lines, f.start, f.end, f.replacement, FixFromStatic))
+			}
+			result, _ := ApplyFixes(lines, fixes)
+			if got := strings.Join(result, " "); got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+			// ApplyFixes 按起始行排序，按排序后的顺序比较
+			var applicable []bool
+			var errs []string
+			for _, f := range fixes {
+				applicable = append(applicable, f.Applicable)
+				errs = append(errs, f.Error)
+			}
+			if !reflect.DeepEqual(applicable, tt.applicable) {
+				t.Errorf("applicable = %v, want %v", applicable, tt.applicable)
+			}
+			if tt.errors != nil && !reflect.DeepEqual(errs, tt.errors) {
+				t.Errorf("errors = %q, want %q", errs, tt.errors)
+			}
+		})
+	}
+}
+
+func TestNewFixOutOfRange(t *testing.T) {
+	lines := []string{"a", "b"}
+	for _, r := range [][2]int{{0, 1}, {2, 1}, {2, 3}} {
+		if fix := NewFix(lines, r[0], r[1], "x", FixFromLLM); fix != nil {
+			t.Errorf("NewFix(%d, %d) = %+v, want nil", r[0], r[1], fix)
+		}
+	}
+}
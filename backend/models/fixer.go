@@ -0,0 +1,228 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// 修复提示词中问题行前后展示的行数
+const fixContextLines = 5
+
+var (
+	thinkRe     = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	codeFenceRe = regexp.MustCompile("(?s)```[\\w+]*\\n(.*?)```")
+)
+
+// 为文件中的问题生成修复：优先使用静态检查器的修复，其余请求 LLM 给出替换代码
+func (c *CodeAnalyzer) generateFixes(path string, lines []string, lang *Language, rules []Rule) {
+	c.Mu.Lock()
+	issues := c.Results[path]
+	c.Mu.Unlock()
+
+	var fixes []*Fix
+	for i := range issues {
+		issue := &issues[i]
+		if issue.Fix == nil {
+			issue.Fix = staticFix(lang, lines, issue)
+		}
+		if issue.Fix == nil {
+			issue.Fix = c.llmFix(lang, lines, issue, rules)
+		}
+		if issue.Fix != nil {
+			fixes = append(fixes, issue.Fix)
+		}
+	}
+	ValidateFixes(lines, fixes)
+
+	c.Mu.Lock()
+	c.Results[path] = issues
+	c.Mu.Unlock()
+	slog.Debug("修复生成完成", "file", path, "fix_count", len(fixes))
+}
+
+// 静态检查器提供的单行修复
+func staticFix(lang *Language, lines []string, issue *Issue) *Fix {
+	if issue.Line < 1 || issue.Line > len(lines) {
+		return nil
+	}
+	for _, checker := range lang.Checkers {
+		if checker.Rule != issue.Rule || checker.Fix == nil {
+			continue
+		}
+		if fixed := checker.Fix(lines[issue.Line-1], lang); fixed != "" {
+			return NewFix(lines, issue.Line, issue.Line, fixed, FixFromStatic)
+		}
+	}
+	return nil
+}
+
+// 请求 LLM 给出问题行的替换代码
+func (c *CodeAnalyzer) llmFix(lang *Language, lines []string, issue *Issue, rules []Rule) *Fix {
+	if issue.Line < 1 || issue.Line > len(lines) {
+		return nil
+	}
+	response, _, err := c.generate(CallFix, c.defaultVoter(), buildFixPrompt(lang, lines, issue, rules), issue.File, nil)
+	if err != nil {
+		slog.Error("LLM生成修复失败", "file", issue.File, "line", issue.Line, "error", err)
+		return nil
+	}
+	replacement, ok := parseFixResponse(response)
+	if !ok {
+		slog.Debug("LLM修复响应中没有代码", "file", issue.File, "line", issue.Line)
+		return nil
+	}
+	return NewFix(lines, issue.Line, issue.Line, replacement, FixFromLLM)
+}
+
+// 构建修复提示词，附带问题行前后的代码和行号
+func buildFixPrompt(lang *Language, lines []string, issue *Issue, rules []Rule) string {
+	ruleText := issue.Rule
+	for _, r := range rules {
+		if r.ID == issue.Rule {
+			ruleText = r.String()
+		}
+	}
+	return fmt.Sprintf(`你是一个%s专家。下面代码的第%d行违反了代码规范。
+违反的规则：%s
+问题描述：%s
+修改建议：%s
+
+代码（行首为行号）：
+%s
+
+请给出替换第%d行的代码，要求：
+1. 只输出替换后的代码，保持原有缩进，可以是多行
+2. 不要修改其他行，不要输出行号和解释
+3. 用三个反引号包裹代码`, lang.DisplayName, issue.Line, ruleText, issue.Original, issue.Suggested,
+		numberLines(lines, issue.Line-fixContextLines, issue.Line+fixContextLines), issue.Line)
+}
+
+// 带行号的代码片段
+func numberLines(lines []string, from, to int) string {
+	from = max(from, 1)
+	to = min(to, len(lines))
+	var b strings.Builder
+	for n := from; n <= to; n++ {
+		fmt.Fprintf(&b, "%d: %s\n", n, lines[n-1])
+	}
+	return b.String()
+}
+
+// 从 LLM 响应中取出代码块，去掉推理模型的思考过程
+func parseFixResponse(response string) (string, bool) {
+	response = thinkRe.ReplaceAllString(response, "")
+	m := codeFenceRe.FindStringSubmatch(response)
+	if m == nil {
+		return "", false
+	}
+	code := strings.TrimRight(m[1], "\n")
+	if strings.TrimSpace(code) == "" {
+		return "", false
+	}
+	return code, true
+}
+
+// 规则2：将 (T)expr 改写为 static_cast<T>(expr)
+func fixCStyleCast(line string, _ *Language) string {
+	m := findCCast(line)
+	if m == nil {
+		return ""
+	}
+	lparen, rparen := castParens(line, m)
+	typ := strings.TrimSpace(line[m[2]:m[3]])
+
+	start := rparen + 1
+	for start < len(line) && line[start] == ' ' {
+		start++
+	}
+	end := operandEnd(line, start)
+	if end <= start {
+		return ""
+	}
+	return line[:lparen] + "static_cast<" + typ + ">(" + line[start:end] + ")" + line[end:]
+}
+
+// 类型转换操作数的结束位置：括号表达式，或带成员访问、下标和调用的标识符
+func operandEnd(line string, i int) int {
+	for i < len(line) && (line[i] == '&' || line[i] == '*') {
+		i++
+	}
+	if i < len(line) && line[i] == '(' {
+		return matchBracket(line, i)
+	}
+	for {
+		begin := i
+		for i < len(line) && (isIdentChar(line[i]) || line[i] == ':') {
+			i++
+		}
+		if i == begin {
+			return begin
+		}
+		for i < len(line) && (line[i] == '(' || line[i] == '[') {
+			if i = matchBracket(line, i); i < 0 {
+				return -1
+			}
+		}
+		switch {
+		case strings.HasPrefix(line[i:], "->"):
+			i += 2
+		case i < len(line) && line[i] == '.':
+			i++
+		default:
+			return i
+		}
+	}
+}
+
+// 匹配括号，返回右括号之后的位置，不匹配时返回 -1
+func matchBracket(line string, i int) int {
+	pairs := map[byte]byte{'(': ')', '[': ']'}
+	closing := pairs[line[i]]
+	depth := 0
+	for j := i; j < len(line); j++ {
+		switch line[j] {
+		case line[i]:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentChar(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+var ptrDeclEndRe = regexp.MustCompile(`\s*;`)
+
+// 规则3：声明时初始化为空指针
+func fixUninitializedPointer(line string, lang *Language) string {
+	m := uninitPtrRe.FindStringSubmatchIndex(line)
+	if m == nil {
+		return ""
+	}
+	null := "nullptr"
+	if lang.Name == "c" {
+		null = "NULL"
+	}
+	loc := ptrDeclEndRe.FindStringIndex(line[m[3]:])
+	if loc == nil {
+		return ""
+	}
+	semicolon := m[3] + loc[0]
+	return line[:semicolon] + " = " + null + line[semicolon:]
+}
+
+// 规则4：裸 except 改为 except Exception
+func fixBareExcept(line string, _ *Language) string {
+	if !bareExceptRe.MatchString(line) {
+		return ""
+	}
+	return strings.Replace(line, "except", "except Exception", 1)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "gorm.io/gorm"
+
+// 扫描任务状态
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// 扫描任务
+type ScanJob struct {
+	gorm.Model
+	FilePath string `json:"file_path"`
+	MD5Low32 string `json:"md5_low32" gorm:"size:32;index"`
+	UserName string `json:"username"`
+	Status   string `json:"status" gorm:"size:16"`
+	Error    string `json:"error,omitempty"`
+	ReportID uint   `json:"report_id"`
+
+	// 门禁阈值：只统计达到该严重级别和置信度的问题
+	MinSeverity   string  `json:"min_severity,omitempty" gorm:"size:16"`
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+
+	// 扫描请求指定的提示词模板版本，如 "gjb-cpp@v3"，未指定的模板按 split 权重分配
+	Prompts []string `json:"prompts,omitempty" gorm:"serializer:json;type:text"`
+
+	// 任务指标：LLM 调用次数及等待限流许可的总时间
+	LLMCalls    int   `json:"llm_calls"`
+	QueueWaitMs int64 `json:"queue_wait_ms"`
+}
+
+// 消息队列中的扫描任务消息
+type ScanMessage struct {
+	JobID    uint   `json:"job_id"`
+	FilePath string `json:"file_path"`
+}
@@ -0,0 +1,235 @@
+package models
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 大文件分块时每块的最大行数
+const maxChunkLines = 500
+
+// 代码块，StartLine 为块之前的行数，块内第 n 行对应文件第 StartLine+n 行
+type Chunk struct {
+	StartLine int
+	Code      string
+}
+
+// 将源码切分为代码块
+type Chunker func(code string) []Chunk
+
+// 语言定义：识别方式、提示词模板、分块方式和静态检查器
+type Language struct {
+	Name        string   // 规则 Languages 中使用的标识，如 "cpp"
+	DisplayName string   // 提示词和报告中展示的名称，如 "C++"
+	Extensions  []string // 小写扩展名，如 ".cpp"
+	Shebangs    []string // 无扩展名脚本的解释器，如 "python3"
+	Prompt      string   // 内置提示词模板，text/template 语法，见 PromptData
+	Chunk       Chunker
+	Checkers    []StaticChecker
+
+	prompt *PromptTemplate
+}
+
+var languages []*Language
+
+// 注册语言，扩展名冲突时后注册的语言生效
+func RegisterLanguage(lang *Language) {
+	lang.prompt = &PromptTemplate{Name: lang.Name, Version: builtinPromptVersion, Language: lang.Name, Text: lang.Prompt}
+	if err := lang.prompt.compile(); err != nil {
+		panic(err)
+	}
+	languages = append(languages, lang)
+}
+
+// 已注册的语言
+func Languages() []*Language {
+	return languages
+}
+
+// 按名称查找语言
+func LanguageByName(name string) *Language {
+	for _, lang := range languages {
+		if lang.Name == name {
+			return lang
+		}
+	}
+	return nil
+}
+
+// 已注册语言的全部扩展名
+func RegisteredExtensions() []string {
+	var exts []string
+	for _, lang := range languages {
+		exts = append(exts, lang.Extensions...)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// 根据扩展名识别语言，无扩展名时读取首行的 shebang，无法识别时返回 nil
+func DetectLanguage(path string) *Language {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != "" {
+		for i := len(languages) - 1; i >= 0; i-- {
+			for _, e := range languages[i].Extensions {
+				if e == ext {
+					return languages[i]
+				}
+			}
+		}
+		return nil
+	}
+
+	interpreter := readShebang(path)
+	if interpreter == "" {
+		return nil
+	}
+	for _, lang := range languages {
+		for _, s := range lang.Shebangs {
+			if s == interpreter {
+				return lang
+			}
+		}
+	}
+	return nil
+}
+
+// 读取 shebang 中的解释器名称，如 "#!/usr/bin/env python3" 返回 "python3"
+func readShebang(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	return interpreter
+}
+
+// 按行数切分，尽量在 boundary 返回 true 的行之后断开
+func splitAtBoundaries(code string, maxLines int, boundary func(lines []string, i int) bool) []Chunk {
+	lines := strings.Split(code, "\n")
+	var chunks []Chunk
+	start := 0
+	for start < len(lines) {
+		end := min(start+maxLines, len(lines))
+		if end < len(lines) {
+			// 在后半块中寻找最后一个边界
+			for i := end - 1; i >= start+maxLines/2; i-- {
+				if boundary(lines, i) {
+					end = i + 1
+					break
+				}
+			}
+		}
+		chunks = append(chunks, Chunk{StartLine: start, Code: strings.Join(lines[start:end], "\n")})
+		start = end
+	}
+	return chunks
+}
+
+// C/C++ 分块：在花括号回到顶层的行之后断开，避免切断函数体
+func chunkCFamily(code string) []Chunk {
+	lines := strings.Split(code, "\n")
+	topLevel := make([]bool, len(lines))
+	depth := 0
+	for i, line := range lines {
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		topLevel[i] = depth <= 0
+	}
+	return splitAtBoundaries(code, maxChunkLines, func(_ []string, i int) bool {
+		return topLevel[i]
+	})
+}
+
+// Python 分块：在下一行是顶层语句时断开
+func chunkPython(code string) []Chunk {
+	return splitAtBoundaries(code, maxChunkLines, func(lines []string, i int) bool {
+		if i+1 >= len(lines) {
+			return true
+		}
+		next := lines[i+1]
+		return next != "" && next[0] != ' ' && next[0] != '\t' && next[0] != '#'
+	})
+}
+
+const cFamilyPrompt = `你是一个{{.Language}}专家，正在检查代码是否符合代码规范。请遵循以下规则：
+{{.RulesText}}
+{{if .ExamplesText}}
+规则示例：
+{{.ExamplesText}}
+{{end}}{{if .Context}}
+相关声明（位于项目其他位置，仅供参考，不要检查）：
+{{.Context}}
+{{end}}
+请分析以下{{.Language}}代码片段：
+
+输出格式要求：
+1. 按行分析，每行格式：[行号]:[规则编号]:[问题描述]:[建议修正]
+2. 如果没有问题，输出"共检查xx行代码，没有问题"
+3. 示例：
+   42:规则2:危险的类型转换:使用static_cast<int>(value)代替(int)value
+
+请开始分析：{{.Code}}`
+
+const pythonPrompt = `你是一个Python专家，正在检查代码是否符合代码规范。请遵循以下规则：
+{{.RulesText}}
+{{if .ExamplesText}}
+规则示例：
+{{.ExamplesText}}
+{{end}}{{if .Context}}
+相关声明（位于项目其他位置，仅供参考，不要检查）：
+{{.Context}}
+{{end}}
+请分析以下Python代码片段：
+
+输出格式要求：
+1. 按行分析，每行格式：[行号]:[规则编号]:[问题描述]:[建议修正]
+2. 如果没有问题，输出"共检查xx行代码，没有问题"
+3. 示例：
+   12:规则4:捕获所有异常:使用except ValueError:代替except:
+
+请开始分析：{{.Code}}`
+
+func init() {
+	RegisterLanguage(&Language{
+		Name:        "c",
+		DisplayName: "C",
+		Extensions:  []string{".c"},
+		Prompt:      cFamilyPrompt,
+		Chunk:       chunkCFamily,
+		Checkers:    []StaticChecker{checkSignedIndex, checkUninitializedPointer},
+	})
+	// .h 默认按 C++ 处理
+	RegisterLanguage(&Language{
+		Name:        "cpp",
+		DisplayName: "C++",
+		Extensions:  []string{".cpp", ".cc", ".cxx", ".c++", ".h", ".hh", ".hpp", ".hxx", ".h++", ".inl", ".ipp"},
+		Prompt:      cFamilyPrompt,
+		Chunk:       chunkCFamily,
+		Checkers:    []StaticChecker{checkSignedIndex, checkCStyleCast, checkUninitializedPointer},
+	})
+	RegisterLanguage(&Language{
+		Name:        "python",
+		DisplayName: "Python",
+		Extensions:  []string{".py", ".pyw"},
+		Shebangs:    []string{"python", "python2", "python3"},
+		Prompt:      pythonPrompt,
+		Chunk:       chunkPython,
+		Checkers:    []StaticChecker{checkBareExcept, checkMutableDefault},
+	})
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 每块的起始行及行数
+func chunkSpans(chunks []Chunk) [][2]int {
+	var spans [][2]int
+	for _, c := range chunks {
+		spans = append(spans, [2]int{c.StartLine, strings.Count(c.Code, "\n") + 1})
+	}
+	return spans
+}
+
+func TestChunkCFamily(t *testing.T) {
+	// 每个函数 100 行，第 3 个函数跨过 maxChunkLines 的前后两半
+	var lines []string
+	for f := 0; f < 7; f++ {
+		lines = append(lines, fmt.Sprintf("void f%d() {", f))
+		for i := 0; i < 98; i++ {
+			lines = append(lines, "  x++;")
+		}
+		lines = append(lines, "}")
+	}
+	chunks := chunkCFamily(strings.Join(lines, "\n"))
+	spans := chunkSpans(chunks)
+	// 在最后一个完整函数之后断开，不切断函数体
+	if len(spans) != 2 || spans[0] != [2]int{0, 500} || spans[1] != [2]int{500, 200} {
+		t.Fatalf("got chunks %v, want [[0 500] [500 200]]", spans)
+	}
+	if !strings.HasPrefix(chunks[1].Code, "void f5() {") {
+		t.Errorf("second chunk starts with %q", strings.SplitN(chunks[1].Code, "\n", 2)[0])
+	}
+
+	// 没有顶层边界时按最大行数切分
+	long := "void f() {\n" + strings.Repeat("  x++;\n", 700) + "}"
+	if spans := chunkSpans(chunkCFamily(long)); len(spans) != 2 || spans[0][1] != maxChunkLines {
+		t.Errorf("got chunks %v, want a full first chunk", spans)
+	}
+	if spans := chunkSpans(chunkCFamily("int x;")); len(spans) != 1 {
+		t.Errorf("got chunks %v for a short file", spans)
+	}
+}
+
+func TestChunkPython(t *testing.T) {
+	var lines []string
+	for f := 0; f < 4; f++ {
+		lines = append(lines, fmt.Sprintf("def f%d():", f))
+		for i := 0; i < 148; i++ {
+			lines = append(lines, "    x += 1")
+		}
+		// 顶层注释不作为断开位置
+		lines = append(lines, "# 注释")
+	}
+	chunks := chunkPython(strings.Join(lines, "\n"))
+	spans := chunkSpans(chunks)
+	if len(spans) != 2 || spans[0] != [2]int{0, 450} {
+		t.Fatalf("got chunks %v, want the first chunk to end before def f3", spans)
+	}
+	if !strings.HasPrefix(chunks[1].Code, "def f3():") {
+		t.Errorf("second chunk starts with %q", strings.SplitN(chunks[1].Code, "\n", 2)[0])
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "扩展名", path: write("a.CPP", "int x;"), want: "cpp"},
+		{name: "头文件按C++处理", path: write("a.h", ""), want: "cpp"},
+		{name: "C", path: write("a.c", ""), want: "c"},
+		{name: "env shebang", path: write("tool", "#!/usr/bin/env python3\nprint(1)\n"), want: "python"},
+		{name: "解释器路径", path: write("run", "#!/usr/local/bin/python -u\n"), want: "python"},
+		{name: "未知解释器", path: write("build", "#!/bin/bash\necho 1\n"), want: ""},
+		{name: "没有 shebang", path: write("README", "python3\n"), want: ""},
+		{name: "只有 env", path: write("empty", "#!/usr/bin/env\n"), want: ""},
+		{name: "未知扩展名不读取 shebang", path: write("a.sh", "#!/usr/bin/env python3\n"), want: ""},
+		{name: "文件不存在", path: filepath.Join(dir, "missing"), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if lang := DetectLanguage(tt.path); lang != nil {
+				got = lang.Name
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
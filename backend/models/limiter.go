@@ -0,0 +1,287 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Redis 中并发许可的默认租期，持有许可期间每隔三分之一租期续租一次，
+// 持有许可的进程异常退出后许可在一个租期内失效
+const inFlightLease = time.Minute
+
+// 等待 Redis 并发许可的轮询间隔
+const inFlightPoll = 200 * time.Millisecond
+
+// 调用 LLM 服务前获取许可，返回的 release 在调用结束后释放许可
+type Limiter interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// 令牌桶，rate 为每秒补充的令牌数
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(max(burst, 1)), tokens: float64(max(burst, 1)), last: time.Now()}
+}
+
+// 取一个令牌，令牌不足时预支，返回需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// 取消预支的令牌
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	d := b.reserve()
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// 进程内的限流：令牌桶限制请求速率，信号量限制同时进行的请求数。
+// rate 或 maxInFlight 不大于 0 时对应的限制不生效
+type LocalLimiter struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+}
+
+func NewLocalLimiter(rate float64, burst, maxInFlight int) *LocalLimiter {
+	l := &LocalLimiter{}
+	if rate > 0 {
+		l.bucket = newTokenBucket(rate, burst)
+	}
+	if maxInFlight > 0 {
+		l.sem = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+func (l *LocalLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// 令牌桶脚本，返回需要等待的毫秒数，为 0 时已取得令牌
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(now - ts, 0) * rate / 1000)
+local wait = 0
+if tokens < 1 then
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+else
+  tokens = tokens - 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait
+`)
+
+// 并发许可脚本：清除过期许可，未达上限时登记许可并返回 1
+var inFlightScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+  redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+  redis.call('PEXPIRE', KEYS[1], ARGV[5])
+  return 1
+end
+return 0
+`)
+
+// 续租脚本：许可仍存在时延长租期并返回 1，已过期被清除时返回 0
+var renewScript = redis.NewScript(`
+if redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  redis.call('ZADD', KEYS[1], 'XX', ARGV[1], ARGV[2])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return 1
+end
+return 0
+`)
+
+var permitSeq atomic.Uint64
+
+// 通过 Redis 在多个进程间共享的限流，行为与 LocalLimiter 相同
+type RedisLimiter struct {
+	Client      *redis.Client
+	Name        string // 提供方名称，用于 Redis 键
+	Rate        float64
+	Burst       int
+	MaxInFlight int
+	Lease       time.Duration // 并发许可租期，为 0 时使用 inFlightLease
+}
+
+func (l *RedisLimiter) lease() time.Duration {
+	if l.Lease > 0 {
+		return l.Lease
+	}
+	return inFlightLease
+}
+
+func (l *RedisLimiter) Acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.MaxInFlight > 0 {
+		key := "llm:inflight:" + l.Name
+		host, _ := os.Hostname()
+		member := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), permitSeq.Add(1))
+		for {
+			now := time.Now()
+			ok, err := inFlightScript.Run(l.Client, []string{key},
+				now.UnixMilli(), l.MaxInFlight, now.Add(l.lease()).UnixMilli(), member, l.lease().Milliseconds()).Int()
+			if err != nil {
+				return nil, fmt.Errorf("获取并发许可失败: %w", err)
+			}
+			if ok == 1 {
+				break
+			}
+			if err := sleepContext(ctx, inFlightPoll); err != nil {
+				return nil, err
+			}
+		}
+		release = l.keepAlive(key, member)
+	}
+
+	if l.Rate > 0 {
+		key := "llm:ratelimit:" + l.Name
+		for {
+			wait, err := tokenBucketScript.Run(l.Client, []string{key}, l.Rate, max(l.Burst, 1), time.Now().UnixMilli()).Int64()
+			if err != nil {
+				release()
+				return nil, fmt.Errorf("获取限流令牌失败: %w", err)
+			}
+			if wait == 0 {
+				break
+			}
+			if err := sleepContext(ctx, time.Duration(wait)*time.Millisecond); err != nil {
+				release()
+				return nil, err
+			}
+		}
+	}
+	return release, nil
+}
+
+// 在调用期间定期续租并发许可，返回的函数停止续租并释放许可。
+// 生成时间超过租期的流式调用因此不会失去许可
+func (l *RedisLimiter) keepAlive(key, member string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(l.lease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				ok, err := renewScript.Run(l.Client, []string{key},
+					now.Add(l.lease()).UnixMilli(), member, l.lease().Milliseconds()).Int()
+				if err != nil {
+					slog.Warn("续租并发许可失败", "provider", l.Name, "error", err)
+				} else if ok == 0 {
+					slog.Warn("并发许可已过期", "provider", l.Name)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			l.Client.ZRem(key, member)
+		})
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 记录调用在限流中等待的时间
+type waitTimer struct {
+	mu    sync.Mutex
+	total time.Duration
+}
+
+type waitTimerKey struct{}
+
+func withWaitTimer(ctx context.Context) (context.Context, *waitTimer) {
+	t := &waitTimer{}
+	return context.WithValue(ctx, waitTimerKey{}, t), t
+}
+
+func recordWait(ctx context.Context, d time.Duration) {
+	if t, ok := ctx.Value(waitTimerKey{}).(*waitTimer); ok {
+		t.mu.Lock()
+		t.total += d
+		t.mu.Unlock()
+	}
+}
+
+func (t *waitTimer) Total() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
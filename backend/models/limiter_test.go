@@ -0,0 +1,190 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(10, 2)
+	// 桶满时可连续取 burst 个令牌
+	for i := 0; i < 2; i++ {
+		if d := b.reserve(); d != 0 {
+			t.Fatalf("第 %d 个令牌需要等待 %v", i+1, d)
+		}
+	}
+	// 之后按速率预支，每个令牌约 100ms
+	if d := b.reserve(); d < 90*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("第 3 个令牌等待 %v, want 约 100ms", d)
+	}
+	if d := b.reserve(); d < 190*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("第 4 个令牌等待 %v, want 约 200ms", d)
+	}
+	// 取消预支后等待时间回退
+	b.cancel()
+	if d := b.reserve(); d < 190*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("取消后重新预支等待 %v, want 约 200ms", d)
+	}
+}
+
+func TestLocalLimiterMaxInFlight(t *testing.T) {
+	l := NewLocalLimiter(0, 0, 2)
+	ctx := context.Background()
+	r1, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 达到上限时阻塞直到超时
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(timeout); err == nil {
+		t.Fatal("超过并发上限时仍取得许可")
+	}
+
+	// 释放后可以再取得
+	r1()
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalLimiterRate(t *testing.T) {
+	l := NewLocalLimiter(20, 1, 0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// 第一个立即取得，之后每个间隔 50ms
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 次调用耗时 %v, want 至少 100ms", elapsed)
+	}
+}
+
+func TestLocalLimiterCancelReleasesSlot(t *testing.T) {
+	l := NewLocalLimiter(1, 1, 1)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	// 令牌耗尽，等待令牌时取消应归还并发许可
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err == nil {
+		t.Fatal("令牌耗尽时仍取得许可")
+	}
+	if len(l.sem) != 0 {
+		t.Errorf("取消后仍占用 %d 个并发许可", len(l.sem))
+	}
+}
+
+func TestLocalLimiterUnlimited(t *testing.T) {
+	l := NewLocalLimiter(0, 0, 0)
+	for i := 0; i < 100; i++ {
+		if _, err := l.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRedisLimiterMaxInFlight(t *testing.T) {
+	client := newTestRedis(t)
+	// 两个实例模拟两个进程共享同一提供方的并发上限
+	a := &RedisLimiter{Client: client, Name: "p", MaxInFlight: 1}
+	b := &RedisLimiter{Client: client, Name: "p", MaxInFlight: 1}
+
+	release, err := a.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := b.Acquire(ctx); err == nil {
+		t.Fatal("另一进程超过并发上限时仍取得许可")
+	}
+
+	release()
+	release() // 重复释放无影响
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := b.Acquire(ctx); err != nil {
+		t.Fatalf("释放后未能取得许可: %v", err)
+	}
+}
+
+func TestRedisLimiterRenewsLease(t *testing.T) {
+	client := newTestRedis(t)
+	lease := 300 * time.Millisecond
+	a := &RedisLimiter{Client: client, Name: "p", MaxInFlight: 1, Lease: lease}
+	b := &RedisLimiter{Client: client, Name: "p", MaxInFlight: 1, Lease: lease}
+
+	release, err := a.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// 调用时间超过租期时许可仍然有效
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lease)
+	defer cancel()
+	if _, err := b.Acquire(ctx); err == nil {
+		t.Fatal("持有期间许可过期，另一进程取得了许可")
+	}
+}
+
+func TestRedisLimiterExpiredLeaseIsReclaimed(t *testing.T) {
+	client := newTestRedis(t)
+	lease := 300 * time.Millisecond
+	// 模拟异常退出的进程：登记许可后不续租也不释放
+	now := time.Now()
+	if err := inFlightScript.Run(client, []string{"llm:inflight:p"},
+		now.UnixMilli(), 1, now.Add(lease).UnixMilli(), "dead", lease.Milliseconds()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &RedisLimiter{Client: client, Name: "p", MaxInFlight: 1, Lease: lease}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lease)
+	defer cancel()
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("过期许可未被回收: %v", err)
+	}
+	release()
+}
+
+func TestRedisLimiterRate(t *testing.T) {
+	client := newTestRedis(t)
+	l := &RedisLimiter{Client: client, Name: "p", Rate: 10, Burst: 1}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("3 次调用耗时 %v, want 至少 200ms", elapsed)
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// LLM 调用用途
+const (
+	CallAnalyze = "analyze"
+	CallFix     = "fix"
+	CallVerify  = "verify"
+)
+
+// LLM 调用结果
+const (
+	CallOK      = "ok"
+	CallAborted = "aborted" // 因循环或超出 token 上限提前终止
+	CallFailed  = "failed"
+)
+
+// 一次 LLM 调用的用量记录
+type LLMCall struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt        time.Time `json:"created_at" gorm:"index"`
+	JobID            uint      `json:"job_id" gorm:"index"`
+	Model            string    `json:"model" gorm:"size:64;index"`
+	Purpose          string    `json:"purpose" gorm:"size:16"`
+	RuleSet          string    `json:"rule_set" gorm:"size:64;index"`
+	File             string    `json:"file"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Estimated        bool      `json:"estimated"`  // 模型未返回用量，token 数为估算值
+	WaitMs           int64     `json:"wait_ms"`    // 等待限流许可的时间
+	LatencyMs        int64     `json:"latency_ms"` // 不含等待时间
+	Retries          int       `json:"retries"`
+	Outcome          string    `json:"outcome" gorm:"size:16;index"`
+	Error            string    `json:"error,omitempty" gorm:"type:text"`
+}
+
+func (LLMCall) TableName() string {
+	return "llm_calls"
+}
+
+// 默认模型对应的投票者
+func (c *CodeAnalyzer) defaultVoter() Voter {
+	name := c.Model
+	if name == "" {
+		name = "default"
+	}
+	return Voter{Name: name, Model: c.Llm}
+}
+
+// 调用模型并记录用量，失败时按 MaxRetries 重试。newStream 不为空时以流式方式调用，
+// 每次尝试使用新的流，返回最后一次尝试的流
+func (c *CodeAnalyzer) generate(purpose string, voter Voter, prompt, filePath string, newStream func() *responseStream, options ...llms.CallOption) (string, *responseStream, error) {
+	call := &LLMCall{Model: voter.Name, Purpose: purpose, RuleSet: c.RuleSetName(), File: filePath}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	ctx, wait := withWaitTimer(c.Ctx)
+	start := time.Now()
+
+	var resp *llms.ContentResponse
+	var stream *responseStream
+	var err error
+	for attempt := 0; ; attempt++ {
+		opts := options
+		if newStream != nil {
+			stream = newStream()
+			opts = append(options[:len(options):len(options)], llms.WithStreamingFunc(stream.write))
+		}
+		resp, err = voter.Model.GenerateContent(ctx, messages, opts...)
+		if err == nil && len(resp.Choices) == 0 {
+			err = errors.New("LLM响应为空")
+		}
+		call.Retries = attempt
+		if err == nil || isAborted(err) || attempt >= c.MaxRetries || c.Ctx.Err() != nil {
+			break
+		}
+		slog.Warn("LLM调用失败，重试", "file", filePath, "model", voter.Name, "attempt", attempt+1, "error", err)
+	}
+	call.WaitMs = wait.Total().Milliseconds()
+	call.LatencyMs = (time.Since(start) - wait.Total()).Milliseconds()
+
+	var content string
+	switch {
+	case err == nil:
+		call.Outcome = CallOK
+		content = resp.Choices[0].Content
+		info := resp.Choices[0].GenerationInfo
+		promptTokens, okPrompt := info["PromptTokens"].(int)
+		completionTokens, okCompletion := info["CompletionTokens"].(int)
+		if okPrompt && okCompletion {
+			call.PromptTokens, call.CompletionTokens = promptTokens, completionTokens
+		} else {
+			call.Estimated = true
+			call.CompletionTokens = estimateTokens(content)
+		}
+	case isAborted(err):
+		call.Outcome, call.Error = CallAborted, err.Error()
+		call.Estimated = true
+		call.CompletionTokens = stream.tokens
+	default:
+		call.Outcome, call.Error = CallFailed, err.Error()
+		call.Estimated = true
+	}
+	if call.Estimated {
+		call.PromptTokens = estimateTokens(prompt)
+	}
+	c.recordCall(call)
+	return content, stream, err
+}
+
+func isAborted(err error) bool {
+	return errors.Is(err, errResponseLooping) || errors.Is(err, errResponseTooLong)
+}
+
+func (c *CodeAnalyzer) recordCall(call *LLMCall) {
+	c.Mu.Lock()
+	onCall := c.OnCall
+	c.Mu.Unlock()
+	if onCall != nil {
+		onCall(call)
+	}
+}
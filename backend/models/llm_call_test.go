@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 返回用量信息的模型，每次调用耗时 delay
+type usageModel struct {
+	scriptedModel
+	promptTokens, completionTokens int
+	delay                          time.Duration
+}
+
+func (m *usageModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	time.Sleep(m.delay)
+	resp, err := m.scriptedModel.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	resp.Choices[0].GenerationInfo = map[string]any{"PromptTokens": m.promptTokens, "CompletionTokens": m.completionTokens}
+	return resp, nil
+}
+
+func TestGenerateRecordsCall(t *testing.T) {
+	const prompt = "检查以下代码：int *p;"
+	const answer = "1:规则3:指针未初始化:初始化为nullptr"
+	tests := []struct {
+		name       string
+		model      llms.Model
+		maxRetries int
+		stream     bool
+		want       LLMCall
+	}{
+		{
+			name:  "模型返回用量",
+			model: &usageModel{scriptedModel: scriptedModel{responses: []string{answer}}, promptTokens: 120, completionTokens: 30},
+			want:  LLMCall{PromptTokens: 120, CompletionTokens: 30, Outcome: CallOK},
+		},
+		{
+			name:  "未返回用量时估算",
+			model: &scriptedModel{responses: []string{answer}},
+			want:  LLMCall{PromptTokens: estimateTokens(prompt), CompletionTokens: estimateTokens(answer), Estimated: true, Outcome: CallOK},
+		},
+		{
+			name:       "重试后成功",
+			model:      &flakyModel{scriptedModel: scriptedModel{responses: []string{answer}}, failures: 2},
+			maxRetries: 2,
+			want:       LLMCall{PromptTokens: estimateTokens(prompt), CompletionTokens: estimateTokens(answer), Estimated: true, Retries: 2, Outcome: CallOK},
+		},
+		{
+			name:       "重试后仍失败",
+			model:      &flakyModel{scriptedModel: scriptedModel{responses: []string{answer}}, failures: 5},
+			maxRetries: 1,
+			want:       LLMCall{PromptTokens: estimateTokens(prompt), Estimated: true, Retries: 1, Outcome: CallFailed, Error: errProviderDown.Error()},
+		},
+		{
+			name:       "循环中止不重试",
+			model:      &scriptedModel{responses: []string{thinkingLoop}},
+			stream:     true,
+			maxRetries: 2,
+			// 第 5 次重复时中止，已生成 <think> 和 5 行
+			want: LLMCall{PromptTokens: estimateTokens(prompt), CompletionTokens: estimateTokens("<think>\n") + 5*estimateTokens("让我再检查一下这一行代码。\n"), Estimated: true, Outcome: CallAborted, Error: errResponseLooping.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []*LLMCall
+			c := &CodeAnalyzer{
+				Model:          "m",
+				MaxRetries:     tt.maxRetries,
+				DefaultRuleSet: "gjb",
+				Ctx:            context.Background(),
+				OnCall:         func(call *LLMCall) { calls = append(calls, call) },
+			}
+			c.Llm = tt.model
+			var newStream func() *responseStream
+			if tt.stream {
+				newStream = func() *responseStream { return newResponseStream(0, nil) }
+			}
+			c.generate(CallAnalyze, c.defaultVoter(), prompt, "a.cpp", newStream)
+
+			if len(calls) != 1 {
+				t.Fatalf("recorded %d calls, want 1", len(calls))
+			}
+			got := *calls[0]
+			if got.Model != "m" || got.Purpose != CallAnalyze || got.RuleSet != "gjb" || got.File != "a.cpp" {
+				t.Errorf("got call %+v, want model m, purpose analyze, rule set gjb, file a.cpp", got)
+			}
+			got.Model, got.Purpose, got.RuleSet, got.File, got.LatencyMs, got.WaitMs = "", "", "", "", 0, 0
+			if got != tt.want {
+				t.Errorf("got call %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRecordsLatency(t *testing.T) {
+	var call *LLMCall
+	c := &CodeAnalyzer{
+		Llm:    &usageModel{scriptedModel: scriptedModel{responses: []string{"OK"}}, delay: 30 * time.Millisecond},
+		Ctx:    context.Background(),
+		OnCall: func(c *LLMCall) { call = c },
+	}
+	c.generate(CallVerify, c.defaultVoter(), "prompt", "a.cpp", nil)
+	if call == nil || call.LatencyMs < 30 || call.WaitMs != 0 || call.Purpose != CallVerify {
+		t.Errorf("got call %+v, want latency of at least 30ms and no wait", call)
+	}
+}
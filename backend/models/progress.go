@@ -0,0 +1,136 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// 任务进度事件类型
+const (
+	ProgressStatus  = "status"
+	ProgressFinding = "finding"
+)
+
+// 每个任务保留的事件数，供稍后订阅的客户端补发
+const maxProgressEvents = 1000
+
+// 任务结束后事件保留的时间
+const progressRetention = 10 * time.Minute
+
+// 订阅者的事件缓冲，缓冲满时丢弃事件，不阻塞扫描
+const progressBuffer = 64
+
+// 扫描任务的进度事件
+type ProgressEvent struct {
+	Type      string    `json:"type"`
+	JobID     uint      `json:"job_id"`
+	Status    string    `json:"status,omitempty"`
+	File      string    `json:"file,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	Rule      string    `json:"rule,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Suggested string    `json:"suggested,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// 进程内的任务进度分发
+type ProgressHub struct {
+	mu   sync.Mutex
+	jobs map[uint]*jobProgress
+}
+
+type jobProgress struct {
+	events      []ProgressEvent
+	subscribers map[chan ProgressEvent]bool
+	closed      bool
+}
+
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{jobs: make(map[uint]*jobProgress)}
+}
+
+func (h *ProgressHub) job(id uint) *jobProgress {
+	p, ok := h.jobs[id]
+	if !ok {
+		p = &jobProgress{subscribers: make(map[chan ProgressEvent]bool)}
+		h.jobs[id] = p
+	}
+	return p
+}
+
+// 发布事件
+func (h *ProgressHub) Publish(event ProgressEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.job(event.JobID)
+	if p.closed {
+		return
+	}
+	if len(p.events) < maxProgressEvents {
+		p.events = append(p.events, event)
+	}
+	for ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// 发布由流式分析初步发现的问题
+func (h *ProgressHub) PublishIssue(jobID uint, issue Issue) {
+	h.Publish(ProgressEvent{
+		Type:      ProgressFinding,
+		JobID:     jobID,
+		File:      issue.File,
+		Line:      issue.Line,
+		Rule:      issue.Rule,
+		Message:   issue.Original,
+		Suggested: issue.Suggested,
+	})
+}
+
+// 订阅任务进度，返回已发生的事件和后续事件的通道。任务结束后通道关闭
+func (h *ProgressHub) Subscribe(jobID uint) ([]ProgressEvent, <-chan ProgressEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.job(jobID)
+	history := append([]ProgressEvent(nil), p.events...)
+	ch := make(chan ProgressEvent, progressBuffer)
+	if p.closed {
+		close(ch)
+		return history, ch, func() {}
+	}
+	p.subscribers[ch] = true
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if p.subscribers[ch] {
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+	}
+	return history, ch, cancel
+}
+
+// 结束任务进度：关闭所有订阅，事件保留一段时间后删除
+func (h *ProgressHub) Close(jobID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.job(jobID)
+	p.closed = true
+	for ch := range p.subscribers {
+		delete(p.subscribers, ch)
+		close(ch)
+	}
+	time.AfterFunc(progressRetention, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.jobs[jobID] == p {
+			delete(h.jobs, jobID)
+		}
+	})
+}
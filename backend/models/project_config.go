@@ -0,0 +1,183 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"standardizer/utils"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 项目根目录下的配置文件名
+const ProjectConfigFile = ".standardizer.yml"
+
+// 项目级配置，示例：
+//
+//	rule_set: gjb
+//	languages: [cpp, python]
+//	include: ["src/**"]
+//	exclude: ["third_party/**", "**/*_generated.cpp"]
+//	extensions: [".cpp", ".h"]
+//	severity:
+//	  规则1: low
+//	disable:
+//	  - path: "legacy/**"
+//	    rules: [规则2]
+type ProjectConfig struct {
+	RuleSet    string            `yaml:"rule_set" json:"rule_set"`
+	Languages  []string          `yaml:"languages" json:"languages,omitempty"` // 为空时扫描所有已注册语言
+	Include    []string          `yaml:"include" json:"include,omitempty"`
+	Exclude    []string          `yaml:"exclude" json:"exclude,omitempty"`
+	Extensions []string          `yaml:"extensions" json:"extensions,omitempty"` // 为空时使用语言注册的扩展名
+	Severity   map[string]string `yaml:"severity" json:"severity,omitempty"`
+	Disable    []PathRules       `yaml:"disable" json:"disable,omitempty"`
+
+	// 项目根目录，配置中的路径均相对于此目录
+	Root string `yaml:"-" json:"-"`
+}
+
+// 对匹配路径禁用的规则
+type PathRules struct {
+	Path  string   `yaml:"path" json:"path"`
+	Rules []string `yaml:"rules" json:"rules"`
+}
+
+// 默认配置：使用默认规则集，扫描所有已注册语言的文件
+func DefaultProjectConfig() *ProjectConfig {
+	cfg, _ := (&ProjectConfig{}).normalize()
+	return cfg
+}
+
+// 读取项目根目录下的配置文件，不存在时使用默认配置
+func LoadProjectConfig(root string) (*ProjectConfig, error) {
+	cfg := &ProjectConfig{Root: root}
+	content, err := os.ReadFile(filepath.Join(root, ProjectConfigFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg.normalize()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", ProjectConfigFile, err)
+	}
+	return cfg.normalize()
+}
+
+// 补全默认值并校验配置
+func (p *ProjectConfig) normalize() (*ProjectConfig, error) {
+	for _, name := range p.Languages {
+		if LanguageByName(name) == nil {
+			return nil, fmt.Errorf("%s 中的语言 %q 不受支持", ProjectConfigFile, name)
+		}
+	}
+	for i, ext := range p.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		p.Extensions[i] = ext
+		// 扩展名须对应已注册且在 languages 中启用的语言，否则这类文件永远不会被扫描
+		lang := DetectLanguage("file" + ext)
+		if lang == nil {
+			return nil, fmt.Errorf("%s 中的扩展名 %q 没有对应的语言，支持的扩展名: %s", ProjectConfigFile, ext, strings.Join(RegisteredExtensions(), " "))
+		}
+		if len(p.Languages) > 0 && !containsString(p.Languages, lang.Name) {
+			return nil, fmt.Errorf("%s 中的扩展名 %q 属于未启用的语言 %s", ProjectConfigFile, ext, lang.Name)
+		}
+	}
+	for rule, severity := range p.Severity {
+		if SeverityRank(severity) == 0 {
+			return nil, fmt.Errorf("%s 中规则 %s 的严重级别 %q 无效", ProjectConfigFile, rule, severity)
+		}
+	}
+	return p, nil
+}
+
+// 相对项目根目录的路径，使用 / 分隔
+func (p *ProjectConfig) relPath(path string) string {
+	if p.Root != "" {
+		if rel, err := filepath.Rel(p.Root, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// 是否扫描该文件，lang 为识别出的语言
+func (p *ProjectConfig) Accepts(path string, lang *Language) bool {
+	if lang == nil {
+		return false
+	}
+	if len(p.Languages) > 0 && !containsString(p.Languages, lang.Name) {
+		return false
+	}
+	rel := p.relPath(path)
+	if len(p.Extensions) > 0 && !containsString(p.Extensions, strings.ToLower(filepath.Ext(rel))) {
+		return false
+	}
+
+	if len(p.Include) > 0 && !matchAny(p.Include, rel) {
+		return false
+	}
+	return !matchAny(p.Exclude, rel)
+}
+
+// 应用严重级别覆盖后的规则集
+func (p *ProjectConfig) ApplySeverity(rules []Rule) []Rule {
+	if len(p.Severity) == 0 {
+		return rules
+	}
+	result := make([]Rule, len(rules))
+	for i, r := range rules {
+		if severity, ok := p.Severity[r.ID]; ok {
+			r.Severity = severity
+		}
+		result[i] = r
+	}
+	return result
+}
+
+// 去掉对该文件禁用的规则
+func (p *ProjectConfig) RulesFor(path string, rules []Rule) []Rule {
+	if len(p.Disable) == 0 {
+		return rules
+	}
+	rel := p.relPath(path)
+	disabled := make(map[string]bool)
+	for _, d := range p.Disable {
+		if utils.MatchGlob(d.Path, rel) {
+			for _, rule := range d.Rules {
+				disabled[rule] = true
+			}
+		}
+	}
+	var result []Rule
+	for _, r := range rules {
+		if !disabled[r.ID] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if utils.MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
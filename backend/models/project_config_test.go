@@ -0,0 +1,142 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadTestProjectConfig(t *testing.T, content string) (*ProjectConfig, error) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ProjectConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadProjectConfig(root)
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantExt []string
+		wantErr string
+	}{
+		{name: "扩展名统一为小写并补全点号", content: "extensions: [CPP, .H]", wantExt: []string{".cpp", ".h"}},
+		{name: "不支持的语言", content: "languages: [java]", wantErr: `语言 "java"`},
+		{name: "没有对应语言的扩展名", content: "extensions: [.java]", wantErr: `扩展名 ".java" 没有对应的语言`},
+		{name: "扩展名属于未启用的语言", content: "languages: [python]\nextensions: [.cpp]", wantErr: "未启用的语言 cpp"},
+		{name: "无效的严重级别", content: "severity:\n  规则1: urgent", wantErr: `严重级别 "urgent" 无效`},
+		{name: "格式错误", content: "languages: cpp: [", wantErr: "解析"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTestProjectConfig(t, tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Extensions, tt.wantExt) {
+				t.Errorf("extensions %v, want %v", cfg.Extensions, tt.wantExt)
+			}
+		})
+	}
+
+	// 没有配置文件时使用默认配置
+	cfg, err := LoadProjectConfig(t.TempDir())
+	if err != nil || cfg.RuleSet != "" || len(cfg.Include) != 0 {
+		t.Errorf("got %+v, %v, want the default config", cfg, err)
+	}
+}
+
+func TestProjectConfigAccepts(t *testing.T) {
+	root := filepath.Join("work", "proj")
+	cfg, err := (&ProjectConfig{
+		Root:       root,
+		Languages:  []string{"cpp", "python"},
+		Include:    []string{"src/**", "tools/*.py"},
+		Exclude:    []string{"src/third_party/**", "**/*_generated.cpp"},
+		Extensions: []string{"CPP", "py"},
+	}).normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "src/main.cpp", want: true},
+		{path: "src/deep/dir/util.CPP", want: true},
+		{path: "src/main.h"},       // 扩展名未列出
+		{path: "src/main.c"},       // 语言未启用
+		{path: "docs/example.cpp"}, // 不在 include 中
+		{path: "tools/gen.py", want: true},
+		{path: "tools/sub/gen.py"}, // * 不跨目录
+		{path: "src/third_party/x.cpp"},
+		{path: "src/a/b_generated.cpp"},
+		{path: "src/README"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := cfg.Accepts(path, DetectLanguage(path)); got != tt.want {
+			t.Errorf("Accepts(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// 默认配置接受所有已注册语言的文件
+	def := DefaultProjectConfig()
+	for _, path := range []string{"a.c", "b/c.hpp", "d.py"} {
+		if !def.Accepts(path, DetectLanguage(path)) {
+			t.Errorf("default config rejected %s", path)
+		}
+	}
+}
+
+func TestProjectConfigRules(t *testing.T) {
+	rules := []Rule{
+		{ID: "规则1", Severity: SeverityHigh},
+		{ID: "规则2", Severity: SeverityMedium},
+		{ID: "规则3", Severity: SeverityMedium},
+	}
+	cfg := &ProjectConfig{
+		Root:     "proj",
+		Severity: map[string]string{"规则1": SeverityLow},
+		Disable: []PathRules{
+			{Path: "legacy/**", Rules: []string{"规则2"}},
+			{Path: "legacy/old/*.cpp", Rules: []string{"规则3"}},
+		},
+	}
+
+	applied := cfg.ApplySeverity(rules)
+	if applied[0].Severity != SeverityLow || applied[1].Severity != SeverityMedium {
+		t.Errorf("got %+v, want 规则1 overridden to low", applied)
+	}
+	if rules[0].Severity != SeverityHigh {
+		t.Error("ApplySeverity modified the rule set")
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "src/a.cpp", want: []string{"规则1", "规则2", "规则3"}},
+		{path: "legacy/a.cpp", want: []string{"规则1", "规则3"}},
+		{path: "legacy/old/a.cpp", want: []string{"规则1"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range cfg.RulesFor(filepath.Join("proj", filepath.FromSlash(tt.path)), rules) {
+			got = append(got, r.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RulesFor(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 未配置时提示词中跨文件声明的 token 预算
+const defaultContextTokens = 400
+
+// 每个标识符最多引用的声明数，避免常见名称占满预算
+const maxDeclsPerName = 5
+
+// 声明种类
+const (
+	DeclTypedef  = "typedef"
+	DeclClass    = "class"
+	DeclMember   = "member"
+	DeclFunction = "function"
+)
+
+// 项目中的一条声明
+type Declaration struct {
+	Name string // 限定名，成员和成员函数为 Class::name
+	Kind string
+	File string
+	Line int
+	Text string // 去掉注释和函数体的声明文本
+}
+
+// 轻量的项目索引：解析后的 #include 关系、类型别名、类成员和函数签名，
+// 用于在分析单个代码块时提供其他位置的声明。只索引 C/C++ 文件
+type ProjectIndex struct {
+	Root     string
+	includes map[string][]string
+	decls    map[string][]Declaration
+	byName   map[string][]Declaration
+}
+
+var (
+	includeRe   = regexp.MustCompile(`^\s*#\s*include\s*"([^"]+)"`)
+	typedefRe   = regexp.MustCompile(`^\s*typedef\s+.+?\b(\w+)\s*(?:\[[^\]]*\])?\s*;`)
+	usingRe     = regexp.MustCompile(`^\s*using\s+(\w+)\s*=\s*[^;]+;`)
+	classRe     = regexp.MustCompile(`^\s*(?:template\s*<[^>]*>\s*)?(?:class|struct|union)\s+(\w+)\b[^;]*$`)
+	namespaceRe = regexp.MustCompile(`^\s*(?:inline\s+)?namespace\b[^;]*$`)
+	memberRe    = regexp.MustCompile(`^\s*(?:(?:static|const|mutable|volatile|constexpr)\s+)*[A-Za-z_][\w:<>,\s]*?[\s*&]+(\w+)\s*(?:\[[^\]]*\])?\s*(?:=[^;]*|\{[^}]*\})?;\s*$`)
+	functionRe  = regexp.MustCompile(`^\s*(?:[\w:<>,*&~]+\s+)*?[*&]*((?:\w+::)*~?\w+)\s*\([^;{}]*\)\s*(?:const\s*)?(?:noexcept\s*)?(?:override\s*)?(?:=\s*0\s*)?(?::\s*[^{;]*)?[{;]?\s*$`)
+)
+
+// 不是声明的语句开头
+var nonDeclPrefixes = []string{"return", "using", "typedef", "friend", "public", "private", "protected", "delete", "throw", "goto", "case", "else"}
+
+// 不是函数名的关键字
+var controlKeywords = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "return": true, "sizeof": true, "catch": true, "do": true}
+
+// 为项目文件建立索引，被包含的头文件即使不在 files 中也会被索引
+func BuildProjectIndex(root string, files []string) *ProjectIndex {
+	ix := &ProjectIndex{
+		Root:     root,
+		includes: make(map[string][]string),
+		decls:    make(map[string][]Declaration),
+		byName:   make(map[string][]Declaration),
+	}
+	queue := append([]string(nil), files...)
+	for len(queue) > 0 {
+		file := filepath.Clean(queue[0])
+		queue = queue[1:]
+		if _, ok := ix.decls[file]; ok {
+			continue
+		}
+		lang := DetectLanguage(file)
+		if lang == nil || lang.Name != "c" && lang.Name != "cpp" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			slog.Warn("索引文件失败", "file", file, "error", err)
+			continue
+		}
+		ix.indexFile(file, strings.Split(string(content), "\n"))
+		queue = append(queue, ix.includes[file]...)
+	}
+	for _, decls := range ix.decls {
+		for _, d := range decls {
+			ix.byName[d.Name] = append(ix.byName[d.Name], d)
+			if i := strings.LastIndex(d.Name, "::"); i >= 0 {
+				ix.byName[d.Name[i+2:]] = append(ix.byName[d.Name[i+2:]], d)
+			}
+		}
+	}
+	slog.Debug("项目索引完成", "root", root, "file_count", len(ix.decls), "name_count", len(ix.byName))
+	return ix
+}
+
+// 解析包含路径：先相对包含文件所在目录，再相对项目根目录及其 include 子目录
+func (ix *ProjectIndex) resolveInclude(from, name string) string {
+	for _, dir := range []string{filepath.Dir(from), ix.Root, filepath.Join(ix.Root, "include")} {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return filepath.Clean(path)
+		}
+	}
+	return ""
+}
+
+// 提取单个文件的包含关系和声明
+func (ix *ProjectIndex) indexFile(file string, lines []string) {
+	decls := []Declaration{}
+	add := func(name, kind string, line int, text string) {
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "{"))
+		decls = append(decls, Declaration{Name: name, Kind: kind, File: file, Line: line, Text: text})
+	}
+
+	// 花括号栈，元素为 namespace、class:<名称> 或 block
+	var stack []string
+	pendingClass := ""
+	for i, line := range stripCLines(lines) {
+		if m := includeRe.FindStringSubmatch(lines[i]); m != nil {
+			if path := ix.resolveInclude(file, m[1]); path != "" {
+				ix.includes[file] = append(ix.includes[file], path)
+			}
+			continue
+		}
+
+		class, depth := "", 0
+		for _, kind := range stack {
+			if kind != "namespace" {
+				depth++
+			}
+		}
+		if n := len(stack); n > 0 && strings.HasPrefix(stack[n-1], "class:") {
+			class = strings.TrimPrefix(stack[n-1], "class:")
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case typedefRe.MatchString(line):
+			add(qualify(class, typedefRe.FindStringSubmatch(line)[1]), DeclTypedef, i+1, line)
+		case usingRe.MatchString(line):
+			add(qualify(class, usingRe.FindStringSubmatch(line)[1]), DeclTypedef, i+1, line)
+		case classRe.MatchString(line) && (depth == 0 || class != ""):
+			name := classRe.FindStringSubmatch(line)[1]
+			pendingClass = qualify(class, name)
+			add(pendingClass, DeclClass, i+1, line)
+		case class != "" && !hasAnyPrefix(trimmed, nonDeclPrefixes):
+			if m := functionRe.FindStringSubmatch(line); m != nil && !controlKeywords[m[1]] {
+				add(class+"::"+m[1], DeclMember, i+1, line)
+			} else if m := memberRe.FindStringSubmatch(line); m != nil {
+				add(class+"::"+m[1], DeclMember, i+1, line)
+			}
+		case depth == 0 && !hasAnyPrefix(trimmed, nonDeclPrefixes):
+			if m := functionRe.FindStringSubmatch(line); m != nil && !controlKeywords[m[1]] {
+				add(m[1], DeclFunction, i+1, line)
+			}
+		}
+
+		for _, ch := range line {
+			switch ch {
+			case '{':
+				kind := "block"
+				if pendingClass != "" {
+					kind, pendingClass = "class:"+pendingClass, ""
+				} else if namespaceRe.MatchString(line) {
+					kind = "namespace"
+				}
+				stack = append(stack, kind)
+			case '}':
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			case ';':
+				// 前置声明
+				pendingClass = ""
+			}
+		}
+	}
+	ix.decls[file] = decls
+}
+
+func qualify(class, name string) string {
+	if class == "" {
+		return name
+	}
+	return class + "::" + name
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) && (len(s) == len(p) || !isIdentChar(s[len(p)])) {
+			return true
+		}
+	}
+	return false
+}
+
+// 文件及其直接或间接包含的文件
+func (ix *ProjectIndex) includeClosure(file string) map[string]bool {
+	seen := map[string]bool{file: true}
+	queue := []string{file}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		for _, inc := range ix.includes[f] {
+			if !seen[inc] {
+				seen[inc] = true
+				queue = append(queue, inc)
+			}
+		}
+	}
+	return seen
+}
+
+// 代码块引用的标识符在项目其他位置的声明，在 token 预算内按相关度排列：
+// 本文件和包含的头文件中的声明优先，其次是项目中其他文件的声明。startLine 为代码块之前的行数
+func (ix *ProjectIndex) Context(file string, startLine int, code string, budget int) string {
+	if ix == nil || budget <= 0 {
+		return ""
+	}
+	file = filepath.Clean(file)
+	endLine := startLine + strings.Count(code, "\n") + 1
+	closure := ix.includeClosure(file)
+
+	type candidate struct {
+		decl     Declaration
+		priority int
+	}
+	seen := make(map[Declaration]bool)
+	var candidates []candidate
+	for _, id := range codeTokenRe.FindAllString(code, -1) {
+		if !isIdentChar(id[0]) {
+			continue
+		}
+		decls := ix.byName[id]
+		if len(decls) > maxDeclsPerName {
+			decls = decls[:maxDeclsPerName]
+		}
+		for _, d := range decls {
+			// 代码块中已有的声明不需要重复提供
+			if seen[d] || d.File == file && d.Line > startLine && d.Line <= endLine {
+				continue
+			}
+			seen[d] = true
+			priority := 1
+			if closure[d.File] {
+				priority = 0
+			}
+			candidates = append(candidates, candidate{decl: d, priority: priority})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].priority < candidates[j].priority
+	})
+
+	var b strings.Builder
+	for _, c := range candidates {
+		line := fmt.Sprintf("%s:%d: %s\n", ix.relPath(c.decl.File), c.decl.Line, c.decl.Text)
+		tokens := estimateTokens(line)
+		if tokens > budget {
+			continue
+		}
+		budget -= tokens
+		b.WriteString(line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (ix *ProjectIndex) relPath(path string) string {
+	if rel, err := filepath.Rel(ix.Root, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
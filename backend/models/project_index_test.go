@@ -0,0 +1,96 @@
+package models
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/project：main.cpp 包含 include/buffer.h（相对项目根目录的 include 子目录解析）和 src/local.h，
+// checksum.cpp 不被 main.cpp 包含
+func buildTestIndex(t *testing.T) (*ProjectIndex, string) {
+	t.Helper()
+	root := filepath.Join("testdata", "project")
+	src := filepath.Join(root, "src")
+	return BuildProjectIndex(root, []string{filepath.Join(src, "main.cpp"), filepath.Join(src, "checksum.cpp")}), root
+}
+
+func TestBuildProjectIndex(t *testing.T) {
+	ix, root := buildTestIndex(t)
+
+	wantIncludes := []string{filepath.Join(root, "include", "buffer.h"), filepath.Join(root, "src", "local.h")}
+	if got := ix.includes[filepath.Join(root, "src", "main.cpp")]; !reflect.DeepEqual(got, wantIncludes) {
+		t.Errorf("includes = %v, want %v", got, wantIncludes)
+	}
+
+	tests := []struct {
+		file string
+		want []string // 名称/种类
+	}{
+		{file: "include/buffer.h", want: []string{
+			"length_t/typedef", "Buffer/class", "Buffer::Buffer/member", "Buffer::Write/member",
+			"Buffer::Size/member", "Buffer::data_/member", "Buffer::size_/member",
+		}},
+		{file: "src/local.h", want: []string{"Header/class", "Header::magic/member", "Header::Tag/typedef"}},
+		// 函数体中的局部变量和语句不是声明
+		{file: "src/main.cpp", want: []string{"Parse/function", "main/function"}},
+		{file: "src/checksum.cpp", want: []string{"Checksum/function"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range ix.decls[filepath.Join(root, filepath.FromSlash(tt.file))] {
+			got = append(got, d.Name+"/"+d.Kind)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s declarations = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	// 成员按类名和短名称都能查到，文本去掉了函数体的左花括号
+	if decls := ix.byName["Size"]; len(decls) != 1 || decls[0].Text != "length_t Size() const;" {
+		t.Errorf("byName[Size] = %+v", decls)
+	}
+	if decls := ix.byName["Checksum"]; len(decls) != 1 || decls[0].Text != "int Checksum(const char *data, length_t size)" {
+		t.Errorf("byName[Checksum] = %+v", decls)
+	}
+}
+
+func TestProjectIndexContext(t *testing.T) {
+	ix, root := buildTestIndex(t)
+	mainFile := filepath.Join(root, "src", "main.cpp")
+	// main.cpp 第 6 到 11 行
+	code := "int main() {\n    io::Buffer buf;\n    int c = Checksum(nullptr, buf.Size());\n    Header h;\n    return Parse(&h) + c;\n}"
+
+	// 本文件及包含的头文件中的声明在前，代码块中的 main 不重复提供
+	want := []string{
+		"include/buffer.h:8: class Buffer",
+		"include/buffer.h:10: Buffer();",
+		"include/buffer.h:12: length_t Size() const;",
+		"src/local.h:1: struct Header",
+		"src/main.cpp:4: static int Parse(Header *h);",
+		"src/checksum.cpp:1: int Checksum(const char *data, length_t size)",
+	}
+	if got := ix.Context(mainFile, 5, code, 400); got != strings.Join(want, "\n") {
+		t.Errorf("context =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// 预算不足时跳过放不下的声明，后面较短的声明仍可放入
+	budget := estimateTokens(want[0]+"\n") + estimateTokens(want[1]+"\n")
+	got := ix.Context(mainFile, 5, code, budget)
+	if lines := strings.Split(got, "\n"); len(lines) != 2 || lines[0] != want[0] || lines[1] != want[1] {
+		t.Errorf("context with budget %d =\n%s", budget, got)
+	}
+	tight := estimateTokens(want[1] + "\n")
+	if got := ix.Context(mainFile, 5, code, tight); got != want[1] {
+		t.Errorf("context with budget %d = %q, want only %q", tight, got, want[1])
+	}
+
+	var empty *ProjectIndex
+	if got := empty.Context(mainFile, 0, code, 400); got != "" {
+		t.Errorf("nil index context = %q", got)
+	}
+	if got := ix.Context(mainFile, 5, code, 0); got != "" {
+		t.Errorf("zero budget context = %q", got)
+	}
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 内置提示词模板的版本，即语言注册时的 Prompt，修改内置模板时递增
+const builtinPromptVersion = "builtin-3"
+
+// 渲染提示词模板的数据
+type PromptData struct {
+	Language  string // 语言展示名称，如 "C++"
+	Rules     []Rule
+	RulesText string // 每行一条规则
+	// 为代码块选出的规则示例
+	Examples     []PromptExample
+	ExamplesText string
+	// 代码块引用的、定义在项目其他位置的声明，每行一条
+	Context string
+	Code    string
+}
+
+// 版本化的提示词模板，使用 text/template 语法，如 {{.Language}}、{{.RulesText}}、{{.Code}}
+type PromptTemplate struct {
+	Name     string `yaml:"name" json:"name"`
+	Version  string `yaml:"version" json:"version"`
+	RuleSet  string `yaml:"rule_set" json:"rule_set,omitempty"` // 为空时适用于所有规则集
+	Language string `yaml:"language" json:"language,omitempty"` // 为空时适用于所有语言
+	Text     string `yaml:"text" json:"text"`
+
+	tmpl *template.Template
+}
+
+// 报告中记录的模板标识，如 "code-review@v2"
+func (p *PromptTemplate) Ref() string {
+	return p.Name + "@" + p.Version
+}
+
+func (p *PromptTemplate) compile() error {
+	tmpl, err := template.New(p.Ref()).Option("missingkey=error").Parse(p.Text)
+	if err != nil {
+		return fmt.Errorf("解析提示词模板 %s 失败: %w", p.Ref(), err)
+	}
+	p.tmpl = tmpl
+	return nil
+}
+
+// 渲染提示词
+func (p *PromptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("渲染提示词模板 %s 失败: %w", p.Ref(), err)
+	}
+	return b.String(), nil
+}
+
+// 解析模板标识，如 "code-review@v2"
+func ParsePromptRef(ref string) (name, version string, ok bool) {
+	name, version, ok = strings.Cut(strings.TrimSpace(ref), "@")
+	return name, version, ok && name != "" && version != ""
+}
+
+// 提示词模板库，示例：
+//
+//	active:
+//	  code-review: v2
+//	split:
+//	  code-review-py: {v1: 50, v2: 50}
+//	templates:
+//	  - name: code-review
+//	    version: v2
+//	    rule_set: gjb
+//	    language: cpp
+//	    text: |
+//	      你是一个{{.Language}}专家……
+//	      {{.RulesText}}
+//	      {{.Code}}
+type PromptLibrary struct {
+	// 每个模板名称使用的版本，未指定时使用最后定义的版本
+	Active map[string]string `yaml:"active" json:"active,omitempty"`
+	// A/B 实验：按权重为每个扫描任务分配模板版本，同一名称不能同时出现在 active 中
+	Split     map[string]map[string]int `yaml:"split" json:"split,omitempty"`
+	Templates []*PromptTemplate         `yaml:"templates" json:"templates"`
+}
+
+// 读取提示词模板库，文件不存在时返回空库，此时使用各语言的内置模板
+func LoadPromptLibrary(path string) (*PromptLibrary, error) {
+	lib := &PromptLibrary{}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lib, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, lib); err != nil {
+		return nil, fmt.Errorf("解析提示词模板库 %s 失败: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range lib.Templates {
+		if p.Name == "" || p.Version == "" {
+			return nil, fmt.Errorf("提示词模板缺少名称或版本")
+		}
+		if seen[p.Ref()] {
+			return nil, fmt.Errorf("提示词模板 %s 重复定义", p.Ref())
+		}
+		seen[p.Ref()] = true
+		if p.Language != "" && LanguageByName(p.Language) == nil {
+			return nil, fmt.Errorf("提示词模板 %s 的语言 %q 不受支持", p.Ref(), p.Language)
+		}
+		if err := p.compile(); err != nil {
+			return nil, err
+		}
+	}
+	for name, version := range lib.Active {
+		if !seen[name+"@"+version] {
+			return nil, fmt.Errorf("启用的提示词模板 %s@%s 不存在", name, version)
+		}
+	}
+	for name, weights := range lib.Split {
+		if _, ok := lib.Active[name]; ok {
+			return nil, fmt.Errorf("提示词模板 %s 不能同时在 active 和 split 中指定", name)
+		}
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("提示词模板 %s 的 split 没有版本", name)
+		}
+		for version, weight := range weights {
+			if !seen[name+"@"+version] {
+				return nil, fmt.Errorf("split 中的提示词模板 %s@%s 不存在", name, version)
+			}
+			if weight <= 0 {
+				return nil, fmt.Errorf("split 中提示词模板 %s@%s 的权重必须为正数", name, version)
+			}
+		}
+	}
+	return lib, nil
+}
+
+// 按标识查找模板，不存在时返回 nil
+func (l *PromptLibrary) Lookup(ref string) *PromptTemplate {
+	if l == nil {
+		return nil
+	}
+	for _, p := range l.Templates {
+		if p.Ref() == ref {
+			return p
+		}
+	}
+	return nil
+}
+
+// 为一次扫描选择各模板的版本。pinned 为扫描请求指定的模板标识，优先使用；
+// 其余在 split 中的模板按权重分配，同一 key 总是得到相同的版本，任务重新排队后不会换版本
+func (l *PromptLibrary) Assign(key string, pinned []string) (map[string]string, error) {
+	versions := make(map[string]string)
+	for _, ref := range pinned {
+		name, version, ok := ParsePromptRef(ref)
+		if !ok || l.Lookup(name+"@"+version) == nil {
+			return nil, fmt.Errorf("提示词模板 %s 不存在", ref)
+		}
+		versions[name] = version
+	}
+	if l == nil {
+		return versions, nil
+	}
+	for name, weights := range l.Split {
+		if _, ok := versions[name]; !ok {
+			versions[name] = pickWeighted(key+"\x00"+name, weights)
+		}
+	}
+	return versions, nil
+}
+
+// 按 key 的哈希在加权的版本中确定性地选择一个
+func pickWeighted(key string, weights map[string]int) string {
+	options := make([]string, 0, len(weights))
+	total := 0
+	for version, weight := range weights {
+		options = append(options, version)
+		total += weight
+	}
+	sort.Strings(options)
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(total))
+	for _, version := range options {
+		if n < weights[version] {
+			return version
+		}
+		n -= weights[version]
+	}
+	return options[len(options)-1]
+}
+
+// 各模板名称当前启用的版本，versions 中为本次扫描分配的版本，优先于 active
+func (l *PromptLibrary) active(versions map[string]string) []*PromptTemplate {
+	byName := make(map[string]*PromptTemplate)
+	var names []string
+	for _, p := range l.Templates {
+		version, ok := versions[p.Name]
+		if !ok {
+			version, ok = l.Active[p.Name]
+		}
+		if ok && version != p.Version {
+			continue
+		}
+		if _, ok := byName[p.Name]; !ok {
+			names = append(names, p.Name)
+		}
+		byName[p.Name] = p
+	}
+	result := make([]*PromptTemplate, len(names))
+	for i, name := range names {
+		result[i] = byName[name]
+	}
+	return result
+}
+
+// 选择适用于规则集和语言的模板，同时指定规则集和语言的模板优先，没有适用模板时返回 nil。
+// versions 为 Assign 为本次扫描分配的版本
+func (l *PromptLibrary) Select(ruleSet, language string, versions map[string]string) *PromptTemplate {
+	if l == nil {
+		return nil
+	}
+	var best *PromptTemplate
+	bestScore := -1
+	for _, p := range l.active(versions) {
+		if p.RuleSet != "" && p.RuleSet != ruleSet || p.Language != "" && p.Language != language {
+			continue
+		}
+		score := 0
+		if p.Language != "" {
+			score += 2
+		}
+		if p.RuleSet != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPromptTemplates = `
+templates:
+  - name: review
+    version: v1
+    text: "v1 {{.Code}}"
+  - name: review
+    version: v2
+    text: "v2 {{.Code}}"
+  - name: review
+    version: v3
+    text: "v3 {{.Code}}"
+`
+
+func loadTestPrompts(t *testing.T, header string) (*PromptLibrary, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompts.yaml")
+	if err := os.WriteFile(path, []byte(header+testPromptTemplates), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadPromptLibrary(path)
+}
+
+func TestLoadPromptLibrarySplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{name: "有效分流", header: "split:\n  review: {v1: 90, v2: 10}\n"},
+		{name: "版本不存在", header: "split:\n  review: {v1: 50, v9: 50}\n", wantErr: "review@v9 不存在"},
+		{name: "权重不是正数", header: "split:\n  review: {v1: 0}\n", wantErr: "权重必须为正数"},
+		{name: "没有版本", header: "split:\n  review: {}\n", wantErr: "没有版本"},
+		{name: "同时指定启用版本", header: "active:\n  review: v1\nsplit:\n  review: {v2: 1}\n", wantErr: "不能同时"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestPrompts(t, tt.header)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPromptLibraryAssign(t *testing.T) {
+	lib, err := loadTestPrompts(t, "split:\n  review: {v1: 75, v2: 25}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一任务总是分配相同的版本，整体比例接近权重
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprint(i)
+		versions, err := lib.Assign(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := lib.Assign(key, nil)
+		if versions["review"] != again["review"] {
+			t.Fatalf("job %s assigned %q then %q", key, versions["review"], again["review"])
+		}
+		counts[versions["review"]]++
+	}
+	if counts["v3"] != 0 || counts["v1"] < 1300 || counts["v1"] > 1700 {
+		t.Errorf("got split %v, want about 1500/500 between v1 and v2", counts)
+	}
+
+	// 指定的版本优先于分流，不在分流中的版本也可以指定
+	versions, err := lib.Assign("1", []string{"review@v3"})
+	if err != nil || versions["review"] != "v3" {
+		t.Errorf("pinned review@v3, got %v (%v)", versions, err)
+	}
+	for _, ref := range []string{"review@v9", "review", "@v1"} {
+		if _, err := lib.Assign("1", []string{ref}); err == nil {
+			t.Errorf("pinned %q, want error", ref)
+		}
+	}
+
+	var empty *PromptLibrary
+	if versions, err := empty.Assign("1", nil); err != nil || len(versions) != 0 {
+		t.Errorf("nil library assigned %v (%v)", versions, err)
+	}
+	if _, err := empty.Assign("1", []string{"review@v1"}); err == nil {
+		t.Error("nil library accepted a pinned version")
+	}
+}
+
+func TestBuildPromptUsesAssignedVersion(t *testing.T) {
+	lib, err := loadTestPrompts(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lang := LanguageByName("cpp")
+	tests := []struct {
+		name     string
+		versions map[string]string
+		want     string
+	}{
+		{name: "默认使用最后定义的版本", want: "review@v3"},
+		{name: "使用分配的版本", versions: map[string]string{"review": "v1"}, want: "review@v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CodeAnalyzer{Prompts: lib, PromptVersions: tt.versions}
+			prompt, _ := c.buildPrompt("int x;", "", nil, lang)
+			if !strings.HasPrefix(prompt, strings.TrimPrefix(tt.want, "review@")+" ") {
+				t.Errorf("got prompt %q, want rendered by %s", prompt, tt.want)
+			}
+			// 报告记录本次扫描实际使用的模板版本
+			if report := c.GenerateReport("."); len(report.Prompts) != 1 || report.Prompts[0] != tt.want {
+				t.Errorf("report prompts %v, want [%s]", report.Prompts, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 未配置时的健康检查参数
+const (
+	defaultHealthInterval   = 10 * time.Second
+	defaultHealthTimeout    = 3 * time.Second
+	defaultFailureThreshold = 3
+)
+
+// 没有可用的 LLM 提供方
+var ErrNoProvider = errors.New("没有可用的LLM服务")
+
+// LLM 服务提供方，即一个 Ollama 服务
+type Provider struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// 调用前获取许可，为空时不限制。同一进程的所有分析器共用
+	Limiter Limiter `json:"-"`
+
+	failures  int
+	open      bool // 熔断：连续失败达到阈值后不再调用，健康检查成功后恢复
+	lastError string
+	checkedAt time.Time
+}
+
+// 提供方的当前状态
+type ProviderStatus struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"last_error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// 按优先级排列的一组提供方，后台定期检查健康状态，
+// 连续失败的提供方熔断，调用时切换到下一个可用的提供方
+type ProviderPool struct {
+	Providers        []*Provider // 第一个为主提供方
+	Interval         time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+	Client           *http.Client
+
+	mu sync.Mutex
+}
+
+func NewProviderPool(providers []*Provider, interval time.Duration, failureThreshold int) *ProviderPool {
+	pool := &ProviderPool{
+		Providers:        providers,
+		Interval:         interval,
+		Timeout:          defaultHealthTimeout,
+		FailureThreshold: failureThreshold,
+		Client:           http.DefaultClient,
+	}
+	if pool.Interval <= 0 {
+		pool.Interval = defaultHealthInterval
+	}
+	if pool.FailureThreshold <= 0 {
+		pool.FailureThreshold = defaultFailureThreshold
+	}
+	return pool
+}
+
+// 启动后台健康检查，ctx 结束时停止
+func (p *ProviderPool) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			p.Check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// 检查所有提供方，请求 Ollama 的模型列表接口
+func (p *ProviderPool) Check(ctx context.Context) {
+	for _, provider := range p.Providers {
+		err := p.ping(ctx, provider)
+		if err != nil {
+			p.ReportFailure(provider, err)
+		} else {
+			p.ReportSuccess(provider)
+		}
+		p.mu.Lock()
+		provider.checkedAt = time.Now()
+		p.mu.Unlock()
+	}
+}
+
+func (p *ProviderPool) ping(ctx context.Context, provider *Provider) error {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(provider.URL, "/")+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("健康检查返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// 记录一次成功，熔断中的提供方恢复
+func (p *ProviderPool) ReportSuccess(provider *Provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if provider.open {
+		slog.Info("LLM服务已恢复", "provider", provider.Name, "url", provider.URL)
+	}
+	provider.failures, provider.open, provider.lastError = 0, false, ""
+}
+
+// 记录一次失败，连续失败达到阈值时熔断
+func (p *ProviderPool) ReportFailure(provider *Provider, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	provider.failures++
+	provider.lastError = err.Error()
+	if !provider.open && provider.failures >= p.FailureThreshold {
+		provider.open = true
+		slog.Warn("LLM服务不可用，已熔断", "provider", provider.Name, "url", provider.URL, "failures", provider.failures, "error", err)
+	}
+}
+
+// 未熔断的提供方，按优先级排列
+func (p *ProviderPool) healthy() []*Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var providers []*Provider
+	for _, provider := range p.Providers {
+		if !provider.open {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// 是否有可用的提供方
+func (p *ProviderPool) Available() bool {
+	return len(p.healthy()) > 0
+}
+
+// 等待直到有可用的提供方
+func (p *ProviderPool) WaitAvailable(ctx context.Context) error {
+	for !p.Available() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
+
+// 各提供方的状态
+func (p *ProviderPool) Status() []ProviderStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]ProviderStatus, 0, len(p.Providers))
+	for _, provider := range p.Providers {
+		status = append(status, ProviderStatus{
+			Name:      provider.Name,
+			URL:       provider.URL,
+			Healthy:   !provider.open,
+			Failures:  provider.failures,
+			LastError: provider.lastError,
+			CheckedAt: provider.checkedAt,
+		})
+	}
+	return status
+}
+
+// 在每个提供方上创建同一模型，调用时自动切换提供方
+func (p *ProviderPool) Model(newModel func(provider *Provider) (llms.Model, error)) (*FailoverModel, error) {
+	m := &FailoverModel{Pool: p, Models: make(map[string]llms.Model)}
+	for _, provider := range p.Providers {
+		model, err := newModel(provider)
+		if err != nil {
+			return nil, err
+		}
+		m.Models[provider.Name] = model
+	}
+	return m, nil
+}
+
+// 按优先级调用各提供方上的模型，当前提供方失败时切换到下一个可用的提供方
+type FailoverModel struct {
+	Pool   *ProviderPool
+	Models map[string]llms.Model // 提供方名称 → 模型
+}
+
+// streamed 不为空且返回 true 时说明失败的调用已输出了部分内容，此时不再切换提供方，
+// 避免下一个提供方的输出接在已输出的内容之后，由调用方用新的流重试
+func (m *FailoverModel) try(ctx context.Context, call func(model llms.Model) error, streamed func() bool) error {
+	lastErr := ErrNoProvider
+	for _, provider := range m.Pool.healthy() {
+		release, err := m.acquire(ctx, provider)
+		if err != nil {
+			return err
+		}
+		err = call(m.Models[provider.Name])
+		release()
+		if err == nil {
+			m.Pool.ReportSuccess(provider)
+			return nil
+		}
+		// 取消或主动中止的调用不是提供方的故障
+		if ctx.Err() != nil || isAborted(err) {
+			return err
+		}
+		m.Pool.ReportFailure(provider, err)
+		if streamed != nil && streamed() {
+			slog.Warn("LLM服务在流式输出中途失败", "provider", provider.Name, "error", err)
+			return err
+		}
+		slog.Warn("LLM服务调用失败，尝试下一个服务", "provider", provider.Name, "error", err)
+		lastErr = err
+	}
+	return lastErr
+}
+
+// 获取提供方的调用许可，等待时间计入调用记录
+func (m *FailoverModel) acquire(ctx context.Context, provider *Provider) (func(), error) {
+	if provider.Limiter == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	release, err := provider.Limiter.Acquire(ctx)
+	recordWait(ctx, time.Since(start))
+	return release, err
+}
+
+func (m *FailoverModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	// 记录流式调用是否已输出内容
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	var streamed atomic.Bool
+	if onChunk := opts.StreamingFunc; onChunk != nil {
+		options = append(options[:len(options):len(options)], llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			streamed.Store(true)
+			return onChunk(ctx, chunk)
+		}))
+	}
+
+	var resp *llms.ContentResponse
+	err := m.try(ctx, func(model llms.Model) error {
+		var err error
+		resp, err = model.GenerateContent(ctx, messages, options...)
+		return err
+	}, streamed.Load)
+	return resp, err
+}
+
+func (m *FailoverModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// 生成嵌入向量，要求各提供方上的模型支持嵌入
+func (m *FailoverModel) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := m.try(ctx, func(model llms.Model) error {
+		embedder, ok := model.(embeddings.EmbedderClient)
+		if !ok {
+			return errors.New("模型不支持生成嵌入向量")
+		}
+		var err error
+		vectors, err = embedder.CreateEmbedding(ctx, texts)
+		return err
+	}, nil)
+	return vectors, err
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+var errProviderDown = errors.New("连接被重置")
+
+// 先流式输出 partial 再失败的模型，failures 次之后正常返回 scriptedModel 的回答
+type flakyModel struct {
+	scriptedModel
+	partial  string
+	failures int
+}
+
+func (m *flakyModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.mu.Lock()
+	fail := m.failures > 0
+	if fail {
+		m.failures--
+	}
+	m.mu.Unlock()
+	if !fail {
+		return m.scriptedModel.GenerateContent(ctx, messages, options...)
+	}
+
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	if m.partial != "" && opts.StreamingFunc != nil {
+		if err := opts.StreamingFunc(ctx, []byte(m.partial)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errProviderDown
+}
+
+func newTestFailover(t *testing.T, primary, secondary llms.Model) *FailoverModel {
+	t.Helper()
+	pool := NewProviderPool([]*Provider{{Name: "primary"}, {Name: "secondary"}}, 0, 0)
+	models := map[string]llms.Model{"primary": primary, "secondary": secondary}
+	m, err := pool.Model(func(provider *Provider) (llms.Model, error) { return models[provider.Name], nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFailoverBeforeStreaming(t *testing.T) {
+	primary := &flakyModel{failures: 1}
+	secondary := &scriptedModel{responses: []string{"1:规则1:a:b\n"}}
+	m := newTestFailover(t, primary, secondary)
+
+	var streamed strings.Builder
+	resp, err := m.GenerateContent(context.Background(), nil, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		streamed.Write(chunk)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Content != "1:规则1:a:b\n" || streamed.String() != "1:规则1:a:b\n" {
+		t.Errorf("got content %q streamed %q, want secondary answer", resp.Choices[0].Content, streamed.String())
+	}
+	if secondary.calls != 1 {
+		t.Errorf("secondary called %d times, want 1", secondary.calls)
+	}
+}
+
+func TestNoFailoverAfterStreaming(t *testing.T) {
+	primary := &flakyModel{partial: "1:规则1:a:b\n", failures: 1}
+	secondary := &scriptedModel{responses: []string{"2:规则2:c:d\n"}}
+	m := newTestFailover(t, primary, secondary)
+
+	var streamed strings.Builder
+	_, err := m.GenerateContent(context.Background(), nil, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		streamed.Write(chunk)
+		return nil
+	}))
+	if !errors.Is(err, errProviderDown) {
+		t.Fatalf("got error %v, want %v", err, errProviderDown)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secondary called %d times, want 0（已输出部分内容后不切换）", secondary.calls)
+	}
+	if streamed.String() != "1:规则1:a:b\n" {
+		t.Errorf("streamed %q", streamed.String())
+	}
+}
+
+func TestStreamCallRetriesWithFreshStream(t *testing.T) {
+	primary := &flakyModel{
+		scriptedModel: scriptedModel{responses: []string{"3:规则3:int *p;:int *p = nullptr;\n"}},
+		partial:       "1:规则1:a:b\n",
+		failures:      1,
+	}
+	m := newTestFailover(t, primary, &scriptedModel{responses: []string{"2:规则2:c:d\n"}})
+	var outcomes []string
+	c := &CodeAnalyzer{
+		Ctx:        context.Background(),
+		MaxRetries: 1,
+		OnCall:     func(call *LLMCall) { outcomes = append(outcomes, call.Outcome) },
+	}
+
+	rules := []Rule{{ID: "规则1"}, {ID: "规则2"}, {ID: "规则3"}}
+	answer, err := c.streamCall(Voter{Name: "m", Model: m}, "prompt", "a.cpp", 0, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "3:规则3:int *p;:int *p = nullptr;\n" {
+		t.Errorf("got answer %q, want only the retried response", answer)
+	}
+	if len(outcomes) != 1 || outcomes[0] != CallOK {
+		t.Errorf("got outcomes %v, want [%s]", outcomes, CallOK)
+	}
+}
@@ -1,14 +1,142 @@
 package models
 
 import (
+	"sort"
 	"time"
-
-	"gorm.io/gorm"
 )
 
+// 报告结构版本，报告字段发生不兼容变化时递增
+const ReportSchemaVersion = 4
+
+// 代码规范检查报告
 type Report struct {
-	gorm.Model
-	MD5Low32  string    `gorm:"size:32"`   // 前端上传文件 MD5 码低 32 位，添加索引
-	Content   string    `gorm:"type:text"` // 报告内容
-	CreatedAt time.Time // 生成时间
+	ID            uint     `json:"id" gorm:"primaryKey"`
+	SchemaVersion int      `json:"schema_version"`
+	JobID         uint     `json:"job_id" gorm:"index"`            // 生成报告的扫描任务
+	MD5Low32      string   `json:"md5_low32" gorm:"size:32;index"` // 前端上传文件 MD5 码低 32 位
+	FileName      string   `json:"file_name"`
+	Title         string   `json:"title"`
+	RuleSet       string   `json:"rule_set,omitempty"`
+	RuleCount     int      `json:"rule_count"`
+	Rules         []Rule   `json:"rules" gorm:"serializer:json;type:text"`
+	Prompts       []string `json:"prompts" gorm:"serializer:json;type:text"` // 使用的提示词模板及版本
+	TotalFiles    int      `json:"total_files"`
+	TotalIssues   int      `json:"total_issues"`      // 未被屏蔽的问题数
+	Suppressed    int      `json:"suppressed_issues"` // 被判定为误报或不修复的问题数
+	// 扫描请求指定的门禁阈值，以及达到阈值的未屏蔽问题数
+	MinSeverity   string        `json:"min_severity,omitempty" gorm:"size:16"`
+	MinConfidence float64       `json:"min_confidence,omitempty"`
+	GateIssues    int           `json:"gate_issues"`
+	Passed        bool          `json:"passed"`
+	Files         []FileResult  `json:"files" gorm:"serializer:json;type:text"`
+	Findings      []Finding     `json:"findings,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Suppressions  []Suppression `json:"suppressions,omitempty" gorm:"constraint:OnDelete:CASCADE"` // 源码中的屏蔽注释
+	CreatedAt     time.Time     `json:"created_at"`                                                // 生成时间
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// 单个文件的检查结果
+type FileResult struct {
+	File         string `json:"file"`
+	FindingCount int    `json:"finding_count"`
+}
+
+// 一条检查发现
+type Finding struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReportID  uint      `json:"report_id" gorm:"index"`
+	File      string    `json:"file" gorm:"size:512;index"`
+	Line      int       `json:"line"`
+	Rule      string    `json:"rule" gorm:"size:64;index"`
+	Severity  string    `json:"severity" gorm:"size:16;index"`
+	Rank      int       `json:"-" gorm:"column:severity_rank;index"` // 严重级别排序值，用于排序和分页
+	Status    string    `json:"status" gorm:"size:16;default:open;index"`
+	Original  string    `json:"original" gorm:"type:text"`
+	Suggested string    `json:"suggested" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 跨扫描识别同一问题的指纹，以及最近一次人工判定
+	Fingerprint   string     `json:"fingerprint" gorm:"size:40;index"`
+	Justification string     `json:"justification,omitempty" gorm:"type:text"`
+	TriagedBy     string     `json:"triaged_by,omitempty"`
+	TriagedAt     *time.Time `json:"triaged_at,omitempty"`
+
+	Votes       int     `json:"votes"`      // 多模型投票中的得票数
+	Confidence  float64 `json:"confidence"` // 得票比例
+	StartColumn int     `json:"start_column"`
+	EndColumn   int     `json:"end_column"`
+	Snippet     string  `json:"snippet" gorm:"type:text"`               // 问题行的源码
+	Engine      string  `json:"engine" gorm:"size:16;index"`            // 发现问题的引擎：static 或 llm
+	Prompt      string  `json:"prompt,omitempty" gorm:"size:128;index"` // LLM 发现的问题所用的提示词模板
+
+	Fix *Fix `json:"fix,omitempty" gorm:"constraint:OnDelete:CASCADE"` // 自动生成的修复
+}
+
+// 是否已被屏蔽，不计入问题统计
+func (f *Finding) Suppressed() bool {
+	return IsSuppressedStatus(f.Status)
+}
+
+// 重新统计问题数，被屏蔽的问题不计入
+func (r *Report) Recount() {
+	counts := make(map[string]int)
+	r.TotalIssues, r.Suppressed, r.GateIssues = 0, 0, 0
+	for i := range r.Findings {
+		if r.Findings[i].Suppressed() {
+			r.Suppressed++
+			continue
+		}
+		r.TotalIssues++
+		counts[r.Findings[i].File]++
+		if r.Findings[i].MeetsGate(r.MinSeverity, r.MinConfidence) {
+			r.GateIssues++
+		}
+	}
+	r.Passed = r.GateIssues == 0
+	for i := range r.Files {
+		r.Files[i].FindingCount = counts[r.Files[i].File]
+	}
+	r.TotalFiles = len(r.Files)
+}
+
+// 扫描请求是否指定了门禁阈值
+func (r *Report) HasGate() bool {
+	return r.MinSeverity != "" || r.MinConfidence > 0
+}
+
+// 严重级别和置信度是否达到门禁阈值，阈值为空时不限制
+func (f *Finding) MeetsGate(minSeverity string, minConfidence float64) bool {
+	return SeverityRank(f.Severity) >= SeverityRank(minSeverity) && f.Confidence >= minConfidence
+}
+
+// 未被屏蔽的检查发现
+func (r *Report) ActiveFindings() []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if !f.Suppressed() {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// 被屏蔽的检查发现，用于报告中的审计部分
+func (r *Report) SuppressedFindings() []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if f.Suppressed() {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// 按文件、行号排序检查发现
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
 }
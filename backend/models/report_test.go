@@ -0,0 +1,93 @@
+package models
+
+import "testing"
+
+func TestFindingMeetsGate(t *testing.T) {
+	tests := []struct {
+		name          string
+		severity      string
+		confidence    float64
+		minSeverity   string
+		minConfidence float64
+		want          bool
+	}{
+		{name: "没有阈值", severity: SeverityLow, want: true},
+		{name: "未知级别没有阈值", severity: "", want: true},
+		{name: "级别恰好等于阈值", severity: SeverityMedium, confidence: 1, minSeverity: SeverityMedium, want: true},
+		{name: "级别低一级", severity: SeverityLow, confidence: 1, minSeverity: SeverityMedium},
+		{name: "高于阈值", severity: SeverityHigh, confidence: 1, minSeverity: SeverityMedium, want: true},
+		{name: "未知级别低于提示", severity: "", confidence: 1, minSeverity: SeverityLow},
+		{name: "置信度恰好等于阈值", severity: SeverityLow, confidence: 2.0 / 3, minConfidence: 2.0 / 3, want: true},
+		{name: "置信度略低于阈值", severity: SeverityLow, confidence: 0.49, minConfidence: 0.5},
+		{name: "两个阈值都需满足", severity: SeverityHigh, confidence: 0.5, minSeverity: SeverityHigh, minConfidence: 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Finding{Severity: tt.severity, Confidence: tt.confidence}
+			if got := f.MeetsGate(tt.minSeverity, tt.minConfidence); got != tt.want {
+				t.Errorf("MeetsGate(%q, %v) = %v, want %v", tt.minSeverity, tt.minConfidence, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportRecount(t *testing.T) {
+	r := &Report{
+		MinSeverity:   SeverityMedium,
+		MinConfidence: 0.5,
+		Files:         []FileResult{{File: "a.cpp"}, {File: "b.cpp"}},
+		Findings: []Finding{
+			{File: "a.cpp", Severity: SeverityMedium, Confidence: 0.5, Status: FindingOpen},
+			{File: "a.cpp", Severity: SeverityLow, Confidence: 1, Status: FindingAccepted},
+			{File: "a.cpp", Severity: SeverityHigh, Confidence: 0.4, Status: FindingOpen},
+			// 被屏蔽的问题不计入门禁
+			{File: "b.cpp", Severity: SeverityHigh, Confidence: 1, Status: FindingFalsePositive},
+			{File: "b.cpp", Severity: SeverityHigh, Confidence: 1, Status: FindingWontFix},
+		},
+	}
+	r.Recount()
+	if r.TotalIssues != 3 || r.Suppressed != 2 || r.GateIssues != 1 || r.Passed {
+		t.Errorf("got total=%d suppressed=%d gate=%d passed=%v, want 3 2 1 false", r.TotalIssues, r.Suppressed, r.GateIssues, r.Passed)
+	}
+	if r.TotalFiles != 2 || r.Files[0].FindingCount != 3 || r.Files[1].FindingCount != 0 {
+		t.Errorf("got files %+v", r.Files)
+	}
+
+	r.Findings[0].Status = FindingWontFix
+	r.Recount()
+	if r.GateIssues != 0 || !r.Passed {
+		t.Errorf("got gate=%d passed=%v after suppressing the only gate issue", r.GateIssues, r.Passed)
+	}
+}
+
+func TestGenerateReportSeverityOverride(t *testing.T) {
+	c := &CodeAnalyzer{
+		Rules: []Rule{
+			{ID: "规则1", Severity: SeverityHigh},
+			{ID: "规则2", Severity: SeverityLow},
+		},
+		Project: &ProjectConfig{Severity: map[string]string{"规则2": SeverityHigh}},
+		Results: map[string][]Issue{
+			"a.cpp": {
+				{Line: 1, Rule: "规则1", Confidence: 1},
+				{Line: 2, Rule: "规则2", Confidence: 1},                        // 项目配置把提示提升为严重
+				{Line: 3, Rule: "规则9", Confidence: 1},                        // 未登记的规则按一般处理
+				{Line: 4, Rule: "规则1", Severity: SeverityLow, Confidence: 1}, // 扫描时确定的级别优先于规则目录
+			},
+		},
+	}
+	report := c.GenerateReport("a.cpp")
+	SortFindings(report.Findings)
+	want := []string{SeverityHigh, SeverityHigh, SeverityMedium, SeverityLow}
+	for i, f := range report.Findings {
+		if f.Severity != want[i] || f.Rank != SeverityRank(want[i]) {
+			t.Errorf("line %d: got severity %q rank %d, want %q", f.Line, f.Severity, f.Rank, want[i])
+		}
+	}
+
+	report.MinSeverity = SeverityHigh
+	report.Recount()
+	if report.GateIssues != 2 {
+		t.Errorf("got %d gate issues at %s, want 2", report.GateIssues, SeverityHigh)
+	}
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// 未配置时每个代码块检索的规则数
+const defaultRetrievalTopK = 8
+
+// 每次请求嵌入的文本数
+const embeddingBatchSize = 32
+
+// 规则检索：将规则描述和示例嵌入到本地向量索引，为每个代码块挑选最相关的规则，
+// 规则目录很大时避免提示词超出模型上下文
+type RuleRetriever struct {
+	Client embeddings.EmbedderClient
+	Model  string // 嵌入模型名称，与索引文件中的模型不同时重新嵌入
+	Path   string // 向量索引文件，为空时只保存在内存中
+	TopK   int
+
+	mu    sync.Mutex
+	index *vectorIndex
+}
+
+// 持久化的向量索引，按文本摘要存储，规则修改后旧向量不再命中
+type vectorIndex struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float32 `json:"vectors"`
+}
+
+// 创建规则检索器并读取已有的向量索引
+func NewRuleRetriever(client embeddings.EmbedderClient, model, path string, topK int) (*RuleRetriever, error) {
+	r := &RuleRetriever{Client: client, Model: model, Path: path, TopK: topK}
+	if r.TopK <= 0 {
+		r.TopK = defaultRetrievalTopK
+	}
+	r.index = &vectorIndex{Model: model, Vectors: make(map[string][]float32)}
+	if path == "" {
+		return r, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index vectorIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("解析向量索引 %s 失败: %w", path, err)
+	}
+	if index.Model != model {
+		slog.Info("嵌入模型已变化，重新建立向量索引", "file", path, "old_model", index.Model, "model", model)
+		return r, nil
+	}
+	if index.Vectors != nil {
+		r.index.Vectors = index.Vectors
+	}
+	return r, nil
+}
+
+// 用于检索的规则文本：规则描述和各违规示例
+func retrievalTexts(r Rule) []string {
+	texts := []string{r.String()}
+	for _, ex := range r.Examples {
+		texts = append(texts, r.ID+" 违规示例：\n"+ex.NonCompliant)
+	}
+	return texts
+}
+
+func textKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// 为索引中没有的规则文本生成向量，有新向量时写回索引文件
+func (r *RuleRetriever) Ensure(ctx context.Context, rules []Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		for _, text := range retrievalTexts(rule) {
+			key := textKey(text)
+			if _, ok := r.index.Vectors[key]; !ok && !seen[key] {
+				seen[key] = true
+				missing = append(missing, text)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(missing); start += embeddingBatchSize {
+		batch := missing[start:min(start+embeddingBatchSize, len(missing))]
+		vectors, err := r.Client.CreateEmbedding(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("生成规则向量失败: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("嵌入模型返回 %d 个向量，应为 %d 个", len(vectors), len(batch))
+		}
+		for i, text := range batch {
+			r.index.Vectors[textKey(text)] = vectors[i]
+		}
+	}
+	slog.Info("规则向量索引已更新", "model", r.Model, "added", len(missing), "total", len(r.index.Vectors))
+	return r.save()
+}
+
+func (r *RuleRetriever) save() error {
+	if r.Path == "" {
+		return nil
+	}
+	content, err := json.Marshal(r.index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, content, 0644)
+}
+
+// 挑选与代码块最相关的 TopK 条规则，规则的相关度取其描述和示例中的最高相似度。
+// 结果保持规则原有顺序，规则数不超过 TopK 时不做检索
+func (r *RuleRetriever) Retrieve(ctx context.Context, code string, rules []Rule) ([]Rule, error) {
+	if len(rules) <= r.TopK {
+		return rules, nil
+	}
+	if err := r.Ensure(ctx, rules); err != nil {
+		return nil, err
+	}
+	vectors, err := r.Client.CreateEmbedding(ctx, []string{code})
+	if err != nil {
+		return nil, fmt.Errorf("生成代码块向量失败: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("嵌入模型返回 %d 个向量，应为 1 个", len(vectors))
+	}
+	query := vectors[0]
+
+	scores := make([]float64, len(rules))
+	r.mu.Lock()
+	for i, rule := range rules {
+		scores[i] = math.Inf(-1)
+		for _, text := range retrievalTexts(rule) {
+			if v, ok := r.index.Vectors[textKey(text)]; ok {
+				scores[i] = math.Max(scores[i], cosine(query, v))
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	order := make([]int, len(rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	order = order[:r.TopK]
+	sort.Ints(order)
+
+	selected := make([]Rule, 0, len(order))
+	for _, i := range order {
+		selected = append(selected, rules[i])
+	}
+	return selected, nil
+}
+
+// 余弦相似度，维度不同或为零向量时返回 0
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
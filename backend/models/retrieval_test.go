@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 按关键词生成向量的嵌入模型：每个关键词一维，值为关键词在文本中出现的次数
+type fakeEmbedder struct {
+	keywords []string
+	batches  []int
+}
+
+func (e *fakeEmbedder) CreateEmbedding(_ context.Context, texts []string) ([][]float32, error) {
+	e.batches = append(e.batches, len(texts))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.keywords))
+		for j, kw := range e.keywords {
+			vectors[i][j] = float32(strings.Count(text, kw))
+		}
+	}
+	return vectors, nil
+}
+
+// 编号为 1..n 的规则，描述依次使用给定的关键词
+func numberedRules(keywords ...string) []Rule {
+	rules := make([]Rule, len(keywords))
+	for i, kw := range keywords {
+		rules[i] = Rule{ID: fmt.Sprintf("规则%d", i+1), Description: "检查" + kw}
+	}
+	return rules
+}
+
+func ruleIDs(rules []Rule) []string {
+	var ids []string
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestRuleRetrieverEnsure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", "rules.json")
+	embedder := &fakeEmbedder{keywords: []string{"x"}}
+	r, err := NewRuleRetriever(embedder, "embed-v1", path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TopK != defaultRetrievalTopK {
+		t.Errorf("TopK = %d, want default %d", r.TopK, defaultRetrievalTopK)
+	}
+
+	// 70 条规则，其中一条带示例，共 71 段文本，分 3 批嵌入
+	var keywords []string
+	for i := 0; i < 70; i++ {
+		keywords = append(keywords, fmt.Sprint("项", i))
+	}
+	rules := numberedRules(keywords...)
+	rules[0].Examples = []RuleExample{{NonCompliant: "int *p;"}}
+	if err := r.Ensure(context.Background(), rules); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embedder.batches, []int{32, 32, 7}) {
+		t.Errorf("batches = %v, want [32 32 7]", embedder.batches)
+	}
+
+	// 已有向量不重复嵌入，只嵌入修改过的规则
+	rules[1].Description = "修改后的描述"
+	if err := r.Ensure(context.Background(), rules); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embedder.batches, []int{32, 32, 7, 1}) {
+		t.Errorf("batches = %v, want one more batch of 1", embedder.batches)
+	}
+
+	// 同一模型重新打开时使用已保存的索引
+	reopened := &fakeEmbedder{keywords: []string{"x"}}
+	r, err = NewRuleRetriever(reopened, "embed-v1", path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Ensure(context.Background(), rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.batches) != 0 || len(r.index.Vectors) != 72 {
+		t.Errorf("reopened index embedded %v with %d vectors, want nothing new", reopened.batches, len(r.index.Vectors))
+	}
+
+	// 嵌入模型变化后全部重新嵌入
+	changed := &fakeEmbedder{keywords: []string{"x"}}
+	r, err = NewRuleRetriever(changed, "embed-v2", path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Ensure(context.Background(), rules); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed.batches, []int{32, 32, 7}) {
+		t.Errorf("batches after model change = %v, want a full re-embed", changed.batches)
+	}
+}
+
+func TestRuleRetrieverRetrieve(t *testing.T) {
+	embedder := &fakeEmbedder{keywords: []string{"指针", "转换", "异常", "循环"}}
+	r, err := NewRuleRetriever(embedder, "embed-v1", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := numberedRules("异常", "转换", "循环", "指针")
+	// 示例中的关键词同样参与相关度
+	rules[0].Examples = []RuleExample{{NonCompliant: "循环 i"}}
+
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		// 规则4 相似度最高，结果仍按规则原有顺序排列
+		{name: "按相似度选出并保持原顺序", code: "指针 指针 转换", want: []string{"规则2", "规则4"}},
+		// 规则1 的示例与规则3 的描述相似度相同，按原顺序取规则1
+		{name: "示例命中", code: "循环 指针 指针", want: []string{"规则1", "规则4"}},
+		{name: "相同分数按原顺序", code: "无关代码", want: []string{"规则1", "规则2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Retrieve(context.Background(), tt.code, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := ruleIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("retrieved %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	// 规则数不超过 TopK 时不调用嵌入模型
+	embedder.batches = nil
+	if got, err := r.Retrieve(context.Background(), "code", rules[:2]); err != nil || len(got) != 2 || len(embedder.batches) != 0 {
+		t.Errorf("small rule set: got %v, %v, embedded %v", ruleIDs(got), err, embedder.batches)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{name: "相同方向", a: []float32{1, 2}, b: []float32{2, 4}, want: 1},
+		{name: "正交", a: []float32{1, 0}, b: []float32{0, 3}, want: 0},
+		{name: "相反方向", a: []float32{1, 1}, b: []float32{-1, -1}, want: -1},
+		{name: "维度不同", a: []float32{1, 0}, b: []float32{1, 0, 0}, want: 0},
+		{name: "零向量", a: []float32{0, 0}, b: []float32{1, 1}, want: 0},
+		{name: "空向量", a: nil, b: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: cosine = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+// 规则严重级别
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// 规则定义
+type Rule struct {
+	ID          string   `json:"id"`                  // 规则编号，如"规则1"
+	Description string   `json:"description"`         // 规则描述
+	Severity    string   `json:"severity"`            // 严重级别
+	Languages   []string `json:"languages,omitempty"` // 适用语言，为空表示适用所有语言
+	Category    string   `json:"category,omitempty"`  // 分类，按分类分片时同类规则放在同一提示词中
+	// 正则表达式，代码块不匹配任何一个时该规则不可能违反，分片时跳过；为空表示总是检查
+	Triggers []string `json:"triggers,omitempty"`
+	// 符合与违反规则的代码示例，作为 few-shot 示例加入提示词
+	Examples []RuleExample `json:"examples,omitempty"`
+}
+
+// 规则的代码示例
+type RuleExample struct {
+	Language     string `json:"language,omitempty"` // 为空表示适用所有语言
+	NonCompliant string `json:"non_compliant"`
+	Compliant    string `json:"compliant"`
+}
+
+// 规则是否适用于该语言
+func (r Rule) AppliesTo(lang string) bool {
+	return len(r.Languages) == 0 || containsString(r.Languages, lang)
+}
+
+// 提示词中的规则文本
+func (r Rule) String() string {
+	return r.ID + ": " + r.Description
+}
+
+// 按规则编号查找严重级别，未登记的规则按一般处理
+func RuleSeverity(rules []Rule, id string) string {
+	for _, r := range rules {
+		if r.ID == id {
+			return r.Severity
+		}
+	}
+	return SeverityMedium
+}
+
+// 严重级别的中文名称
+func SeverityLabel(severity string) string {
+	switch severity {
+	case SeverityHigh:
+		return "严重"
+	case SeverityLow:
+		return "提示"
+	default:
+		return "一般"
+	}
+}
+
+// 严重级别排序值，越严重越大
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	default:
+		return 0
+	}
+}
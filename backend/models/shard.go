@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+)
+
+// 规则分片方式
+const (
+	ShardByCategory = "category" // 同一分类的规则放在一个分片
+	ShardByTokens   = "tokens"   // 按规则文本的 token 预算装箱
+)
+
+// 未配置时每个分片中规则文本的 token 预算
+const defaultShardTokens = 800
+
+// 规则分片配置：规则较多时每个代码块按分片分别请求 LLM，并跳过明显不适用的规则
+type Sharding struct {
+	Mode        string // 为空时所有规则放在一个提示词中
+	ShardTokens int    // 按 token 分片时每个分片的预算
+}
+
+// 检查分片方式是否有效
+func (s Sharding) Validate() error {
+	switch s.Mode {
+	case "", ShardByCategory, ShardByTokens:
+		return nil
+	}
+	return fmt.Errorf("不支持的规则分片方式: %s", s.Mode)
+}
+
+var (
+	triggerMu    sync.Mutex
+	triggerCache = make(map[string]*regexp.Regexp)
+)
+
+// 编译并缓存触发条件，无效的表达式视为总是匹配
+func triggerRegexp(pattern string) *regexp.Regexp {
+	triggerMu.Lock()
+	defer triggerMu.Unlock()
+	re, ok := triggerCache[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			slog.Warn("规则触发条件无效", "pattern", pattern, "error", err)
+		}
+		triggerCache[pattern] = re
+	}
+	return re
+}
+
+// 规则是否可能适用于代码块
+func (r Rule) MayApply(code string) bool {
+	if len(r.Triggers) == 0 {
+		return true
+	}
+	for _, pattern := range r.Triggers {
+		re := triggerRegexp(pattern)
+		if re == nil || re.MatchString(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// 将规则分片
+func shardRules(rules []Rule, sharding Sharding) [][]Rule {
+	switch sharding.Mode {
+	case ShardByCategory:
+		var shards [][]Rule
+		index := make(map[string]int)
+		for _, r := range rules {
+			i, ok := index[r.Category]
+			if !ok {
+				i = len(shards)
+				index[r.Category] = i
+				shards = append(shards, nil)
+			}
+			shards[i] = append(shards[i], r)
+		}
+		return shards
+	case ShardByTokens:
+		budget := sharding.ShardTokens
+		if budget <= 0 {
+			budget = defaultShardTokens
+		}
+		var shards [][]Rule
+		var shard []Rule
+		used := 0
+		for _, r := range rules {
+			tokens := estimateTokens(r.String())
+			if len(shard) > 0 && used+tokens > budget {
+				shards = append(shards, shard)
+				shard, used = nil, 0
+			}
+			shard = append(shard, r)
+			used += tokens
+		}
+		if len(shard) > 0 {
+			shards = append(shards, shard)
+		}
+		return shards
+	default:
+		return [][]Rule{rules}
+	}
+}
+
+// 代码块需要检查的规则分片，未启用分片时返回全部规则
+func (c *CodeAnalyzer) ruleShards(code string, rules []Rule) [][]Rule {
+	if c.Sharding.Mode == "" {
+		return [][]Rule{rules}
+	}
+	var shards [][]Rule
+	for _, shard := range shardRules(rules, c.Sharding) {
+		var applicable []Rule
+		for _, r := range shard {
+			if r.MayApply(code) {
+				applicable = append(applicable, r)
+			}
+		}
+		if len(applicable) > 0 {
+			shards = append(shards, applicable)
+		}
+	}
+	return shards
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 各分片的规则编号
+func shardIDs(shards [][]Rule) [][]string {
+	var ids [][]string
+	for _, shard := range shards {
+		ids = append(ids, ruleIDs(shard))
+	}
+	return ids
+}
+
+func TestShardRules(t *testing.T) {
+	rules := []Rule{
+		{ID: "规则1", Description: "数组索引必须使用无符号类型", Category: "类型"},
+		{ID: "规则2", Description: "禁止C风格强制类型转换", Category: "类型"},
+		{ID: "规则3", Description: "指针声明时必须初始化", Category: "指针"},
+		{ID: "规则4", Description: "禁止使用 goto"},
+		{ID: "规则5", Description: "指针使用前必须判空", Category: "指针"},
+	}
+	twoRules := estimateTokens(rules[0].String()) + estimateTokens(rules[1].String())
+
+	tests := []struct {
+		name     string
+		sharding Sharding
+		want     [][]string
+	}{
+		{name: "不分片", want: [][]string{{"规则1", "规则2", "规则3", "规则4", "规则5"}}},
+		// 分片按分类首次出现的顺序排列，没有分类的规则单独一片
+		{name: "按分类", sharding: Sharding{Mode: ShardByCategory}, want: [][]string{{"规则1", "规则2"}, {"规则3", "规则5"}, {"规则4"}}},
+		{name: "按 token 预算", sharding: Sharding{Mode: ShardByTokens, ShardTokens: twoRules}, want: [][]string{{"规则1", "规则2"}, {"规则3", "规则4"}, {"规则5"}}},
+		// 超出预算的单条规则仍单独成片
+		{name: "预算小于单条规则", sharding: Sharding{Mode: ShardByTokens, ShardTokens: 1}, want: [][]string{{"规则1"}, {"规则2"}, {"规则3"}, {"规则4"}, {"规则5"}}},
+		{name: "默认预算", sharding: Sharding{Mode: ShardByTokens}, want: [][]string{{"规则1", "规则2", "规则3", "规则4", "规则5"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shardIDs(shardRules(rules, tt.sharding)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shards = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := (Sharding{Mode: "random"}).Validate(); err == nil {
+		t.Error("unknown sharding mode accepted")
+	}
+}
+
+func TestRuleMayApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers []string
+		code     string
+		want     bool
+	}{
+		{name: "没有触发条件", code: "x = 1", want: true},
+		{name: "匹配", triggers: []string{`\*\s*\w+\s*;`}, code: "int *p;", want: true},
+		{name: "任一匹配", triggers: []string{`goto`, `\(\s*int\s*\)`}, code: "y = (int)x;", want: true},
+		{name: "都不匹配", triggers: []string{`goto`, `\[`}, code: "x = 1;"},
+		{name: "无效表达式视为匹配", triggers: []string{`(`}, code: "x = 1;", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Rule{ID: "规则1", Triggers: tt.triggers}).MayApply(tt.code); got != tt.want {
+				t.Errorf("MayApply = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleShardsSkipsInapplicableRules(t *testing.T) {
+	rules := []Rule{
+		{ID: "规则1", Category: "类型", Triggers: []string{`\[`}},
+		{ID: "规则2", Category: "类型", Triggers: []string{`\(\s*\w+\s*\*?\)`}},
+		{ID: "规则3", Category: "指针", Triggers: []string{`\*`}},
+		{ID: "规则4", Category: "控制", Triggers: []string{`\bgoto\b`}},
+	}
+	tests := []struct {
+		name     string
+		sharding Sharding
+		code     string
+		want     [][]string
+	}{
+		// 整个分片都不适用时跳过，部分适用时只保留适用的规则
+		{name: "按分类过滤", sharding: Sharding{Mode: ShardByCategory}, code: "int *p = (int*)q;", want: [][]string{{"规则2"}, {"规则3"}}},
+		{name: "没有适用规则", sharding: Sharding{Mode: ShardByCategory}, code: "x = 1;"},
+		// 未启用分片时不做过滤
+		{name: "未启用分片", code: "x = 1;", want: [][]string{{"规则1", "规则2", "规则3", "规则4"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CodeAnalyzer{Sharding: tt.sharding}
+			if got := shardIDs(c.ruleShards(tt.code, rules)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shards = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// 未配置时单次响应的 token 上限
+const defaultMaxResponseTokens = 8192
+
+// 同一行重复出现超过该次数时认为模型陷入循环
+const maxRepeatedLines = 4
+
+// 参与循环检测的最短行，避免空行、代码围栏等短行误判
+const minRepeatedLineLen = 8
+
+// 推理模型输出思考过程的标记
+const (
+	thinkOpen = "<think>"
+	thinkEnd  = "</think>"
+)
+
+var (
+	errResponseLooping = errors.New("LLM响应出现重复循环")
+	errResponseTooLong = errors.New("LLM响应超出 token 上限")
+
+	// 生成因循环或超出上限被提前终止，回答不完整
+	ErrAborted = errors.New("LLM生成被提前终止")
+)
+
+// 流式响应：按行切分收到的内容，对思考过程之外的每一行调用 onLine 并记入 answer，
+// 响应超出 token 上限或出现循环时返回错误以中止生成
+type responseStream struct {
+	maxTokens int
+	onLine    func(line string)
+
+	text     strings.Builder
+	answer   strings.Builder
+	pending  string
+	tokens   int
+	thinking bool
+	repeats  map[string]int
+}
+
+func newResponseStream(maxTokens int, onLine func(string)) *responseStream {
+	return &responseStream{maxTokens: maxTokens, onLine: onLine, repeats: make(map[string]int)}
+}
+
+func (s *responseStream) write(_ context.Context, chunk []byte) error {
+	s.text.Write(chunk)
+	s.tokens += estimateTokens(string(chunk))
+	if s.maxTokens > 0 && s.tokens > s.maxTokens {
+		return errResponseTooLong
+	}
+
+	s.pending += string(chunk)
+	for {
+		i := strings.IndexByte(s.pending, '\n')
+		if i < 0 {
+			return nil
+		}
+		line := s.pending[:i]
+		s.pending = s.pending[i+1:]
+		if err := s.line(line); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *responseStream) line(line string) error {
+	if strings.Contains(line, thinkOpen) {
+		s.thinking = true
+	}
+	if strings.Contains(line, thinkEnd) {
+		s.thinking = false
+		line = line[strings.Index(line, thinkEnd)+len(thinkEnd):]
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) >= minRepeatedLineLen {
+		s.repeats[trimmed]++
+		if s.repeats[trimmed] > maxRepeatedLines {
+			return errResponseLooping
+		}
+	}
+	if s.thinking {
+		return nil
+	}
+	s.answer.WriteString(line + "\n")
+	if trimmed != "" && s.onLine != nil {
+		s.onLine(line)
+	}
+	return nil
+}
+
+// 处理最后一行。模型不支持流式输出时整个响应一次性交给 finish
+func (s *responseStream) finish(response string) {
+	if s.text.Len() == 0 {
+		s.pending = response
+	}
+	// 以换行结尾时没有未处理的最后一行
+	if s.pending != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(s.pending, "\n"), "\n") {
+			if s.line(line) != nil {
+				break
+			}
+		}
+	}
+	s.pending = ""
+}
+
+// 以流式方式调用模型，边生成边解析问题，返回思考过程之外的回答。
+// 因循环或超出上限中止时返回已生成的部分和 ErrAborted
+func (c *CodeAnalyzer) streamCall(voter Voter, prompt, filePath string, startLine int, rules []Rule, options ...llms.CallOption) (string, error) {
+	maxTokens := c.MaxResponseTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxResponseTokens
+	}
+	newStream := func() *responseStream {
+		return newResponseStream(maxTokens, func(line string) {
+			for _, issue := range keepRules(parseLLMResponse(line, filePath, startLine), rules) {
+				c.emitFinding(issue)
+			}
+		})
+	}
+
+	response, stream, err := c.generate(CallAnalyze, voter, prompt, filePath, newStream, options...)
+	if isAborted(err) {
+		slog.Warn("提前终止LLM生成", "file", filePath, "model", voter.Name, "tokens", stream.tokens, "reason", err)
+		return stream.answer.String(), fmt.Errorf("%w: %v", ErrAborted, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	stream.finish(response)
+	return stream.answer.String(), nil
+}
+
+// 推送初步发现的问题，同一位置同一规则只推送一次。多模型投票时最终结果可能不包含该问题
+func (c *CodeAnalyzer) emitFinding(issue Issue) {
+	c.Mu.Lock()
+	if c.OnFinding == nil {
+		c.Mu.Unlock()
+		return
+	}
+	if c.emitted == nil {
+		c.emitted = make(map[string]bool)
+	}
+	key := fmt.Sprintf("%s:%d:%s", issue.File, issue.Line, issue.Rule)
+	if c.emitted[key] {
+		c.Mu.Unlock()
+		return
+	}
+	c.emitted[key] = true
+	onFinding := c.OnFinding
+	c.Mu.Unlock()
+	onFinding(issue)
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 按给定的分片依次写入流，返回第一个错误
+func feedStream(s *responseStream, chunks []string) error {
+	for _, chunk := range chunks {
+		if err := s.write(context.Background(), []byte(chunk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestResponseStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxTokens  int
+		chunks     []string
+		wantErr    error
+		wantLines  []string
+		wantAnswer string
+	}{
+		{
+			name:       "分片中的行",
+			chunks:     []string{"3:规则1:有符号", "索引:改用size_t\n5:规则2", ":C风格转换:static_cast\n"},
+			wantLines:  []string{"3:规则1:有符号索引:改用size_t", "5:规则2:C风格转换:static_cast"},
+			wantAnswer: "3:规则1:有符号索引:改用size_t\n5:规则2:C风格转换:static_cast\n",
+		},
+		{
+			name:       "思考结束标记跨分片",
+			chunks:     []string{"<thi", "nk>\n7:规则3:思考中复述的问题:不应推送\n</th", "ink>2:规则3:指针未初始化:初始化为nullptr\n"},
+			wantLines:  []string{"2:规则3:指针未初始化:初始化为nullptr"},
+			wantAnswer: "2:规则3:指针未初始化:初始化为nullptr\n",
+		},
+		{
+			name:       "思考过程中的重复行也会中止",
+			chunks:     []string{"<think>\n", strings.Repeat("让我再检查一下这一行代码。\n", 5)},
+			wantErr:    errResponseLooping,
+			wantAnswer: "",
+		},
+		{
+			name:       "回答中重复的行在超过上限时中止",
+			chunks:     []string{"1:规则1:问题描述一:修正\n", strings.Repeat("4:规则2:重复的问题:修正\n", 6)},
+			wantErr:    errResponseLooping,
+			wantLines:  []string{"1:规则1:问题描述一:修正", "4:规则2:重复的问题:修正", "4:规则2:重复的问题:修正", "4:规则2:重复的问题:修正", "4:规则2:重复的问题:修正"},
+			wantAnswer: "1:规则1:问题描述一:修正\n" + strings.Repeat("4:规则2:重复的问题:修正\n", 4),
+		},
+		{
+			name:       "短行不参与循环检测",
+			chunks:     []string{strings.Repeat("```\n\n", 10)},
+			wantAnswer: strings.Repeat("```\n\n", 10),
+			wantLines:  strings.Split(strings.Repeat("```,", 10), ",")[:10],
+		},
+		{
+			name:       "超出 token 上限",
+			maxTokens:  20,
+			chunks:     []string{"1:规则1:问题描述一:修正\n", strings.Repeat("这是很长的一段输出", 10)},
+			wantErr:    errResponseTooLong,
+			wantLines:  []string{"1:规则1:问题描述一:修正"},
+			wantAnswer: "1:规则1:问题描述一:修正\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			s := newResponseStream(tt.maxTokens, func(line string) { lines = append(lines, line) })
+			err := feedStream(s, tt.chunks)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				s.finish("")
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %q, want %q", lines, tt.wantLines)
+			}
+			if got := s.answer.String(); got != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", got, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestResponseStreamFinish(t *testing.T) {
+	// 最后一行没有换行符
+	s := newResponseStream(0, nil)
+	if err := feedStream(s, []string{"<think>推理</think>\n1:规则1:a:b\n2:规则2:c:d"}); err != nil {
+		t.Fatal(err)
+	}
+	s.finish("ignored")
+	if got := s.answer.String(); got != "\n1:规则1:a:b\n2:规则2:c:d\n" {
+		t.Errorf("answer = %q", got)
+	}
+
+	// 模型不支持流式输出时使用完整响应
+	s = newResponseStream(0, nil)
+	s.finish("<think>\n推理\n</think>\nOK")
+	if got := s.answer.String(); got != "\nOK\n" {
+		t.Errorf("answer = %q", got)
+	}
+}
+
+func TestStreamCallAbortsOverTokenLimit(t *testing.T) {
+	response := "2:规则3:指针未初始化:初始化为nullptr\n" + strings.Repeat("继续输出很长的无关内容\n", 50)
+	c := &CodeAnalyzer{
+		Llm:               &scriptedModel{responses: []string{response}},
+		MaxResponseTokens: 40,
+		Ctx:               context.Background(),
+	}
+	var emitted []Issue
+	c.OnFinding = func(issue Issue) { emitted = append(emitted, issue) }
+	answer, err := c.streamCall(c.defaultVoter(), "prompt", "a.cpp", 10, []Rule{{ID: "规则3"}})
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("err = %v, want ErrAborted", err)
+	}
+	if !strings.HasPrefix(answer, "2:规则3:") {
+		t.Errorf("answer = %q, want the lines before the limit", answer)
+	}
+	// 中止前解析出的问题已推送，行号加上代码块的起始行
+	if len(emitted) != 1 || emitted[0].Line != 12 {
+		t.Errorf("emitted %+v, want one issue on line 12", emitted)
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 源码内屏蔽范围
+const (
+	SuppressLine = "line"
+	SuppressFile = "file"
+)
+
+// 源码中的屏蔽注释，如
+//
+//	int *p; // standardizer:ignore 规则3 由调用方初始化
+//	// standardizer:ignore-file 规则2 第三方生成代码
+//
+// 行尾注释屏蔽当前行，单独成行的注释屏蔽下一行；不写规则时屏蔽所有规则
+type Suppression struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	ReportID uint   `json:"report_id" gorm:"index"`
+	File     string `json:"file" gorm:"size:512"`
+	Line     int    `json:"line"`         // 被屏蔽的行，整个文件屏蔽时为 0
+	Comment  int    `json:"comment_line"` // 注释所在行
+	Scope    string `json:"scope" gorm:"size:8"`
+	Rules    string `json:"rules"` // 逗号分隔的规则编号，为空表示所有规则
+	Reason   string `json:"reason" gorm:"type:text"`
+	Matched  int    `json:"matched"` // 实际屏蔽的问题数
+}
+
+var (
+	suppressionRe = regexp.MustCompile(`(?://|/\*|#)\s*standardizer:(ignore-file|ignore)\b(.*)$`)
+	ruleListRe    = regexp.MustCompile(`^规则\d+(,规则\d+)*$`)
+	ruleLikeRe    = regexp.MustCompile(`^规则\d`) // 以规则编号开头但格式不符的列表
+)
+
+// 解析源码中的屏蔽注释
+func ParseSuppressions(file string, lines []string) []Suppression {
+	var suppressions []Suppression
+	for i, line := range lines {
+		m := suppressionRe.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		directive := line[m[2]:m[3]]
+		rest := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line[m[4]:m[5]]), "*/"))
+
+		s := Suppression{File: file, Comment: i + 1}
+		if fields := strings.Fields(rest); len(fields) > 0 && ruleListRe.MatchString(fields[0]) {
+			s.Rules = fields[0]
+			s.Reason = strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+		} else if len(fields) > 0 && ruleLikeRe.MatchString(fields[0]) {
+			// 规则列表格式错误时忽略该注释，避免误屏蔽所有规则
+			continue
+		} else {
+			s.Reason = rest
+		}
+
+		switch {
+		case directive == "ignore-file":
+			s.Scope = SuppressFile
+		case strings.TrimSpace(line[:m[0]]) != "":
+			// 行尾注释屏蔽当前行
+			s.Scope = SuppressLine
+			s.Line = i + 1
+		default:
+			// 单独成行的注释屏蔽下一行
+			s.Scope = SuppressLine
+			s.Line = i + 2
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions
+}
+
+// 是否屏蔽该问题
+func (s *Suppression) Matches(issue Issue) bool {
+	if s.Scope == SuppressLine && s.Line != issue.Line {
+		return false
+	}
+	if s.Rules == "" {
+		return true
+	}
+	for _, rule := range strings.Split(s.Rules, ",") {
+		if rule == issue.Rule {
+			return true
+		}
+	}
+	return false
+}
+
+// 过滤被屏蔽的问题，并记录每条屏蔽注释命中的次数
+func ApplySuppressions(issues []Issue, suppressions []Suppression) []Issue {
+	if len(suppressions) == 0 {
+		return issues
+	}
+	kept := issues[:0]
+	for _, issue := range issues {
+		suppressed := false
+		for i := range suppressions {
+			if suppressions[i].Matches(issue) {
+				suppressions[i].Matched++
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
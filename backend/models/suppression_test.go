@@ -0,0 +1,139 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Suppression
+	}{
+		{
+			name:  "行尾注释屏蔽当前行",
+			lines: []string{"int a;", "int *p; // standardizer:ignore 规则3 由调用方初始化"},
+			want:  []Suppression{{File: "f", Line: 2, Comment: 2, Scope: SuppressLine, Rules: "规则3", Reason: "由调用方初始化"}},
+		},
+		{
+			name:  "单独成行的注释屏蔽下一行",
+			lines: []string{"  // standardizer:ignore 规则1,规则2 历史接口", "int x = (int)y;"},
+			want:  []Suppression{{File: "f", Line: 2, Comment: 1, Scope: SuppressLine, Rules: "规则1,规则2", Reason: "历史接口"}},
+		},
+		{
+			name:  "不写规则时屏蔽所有规则",
+			lines: []string{"foo(); // standardizer:ignore 第三方宏"},
+			want:  []Suppression{{File: "f", Line: 1, Comment: 1, Scope: SuppressLine, Reason: "第三方宏"}},
+		},
+		{
+			name:  "屏蔽整个文件的指定规则",
+			lines: []string{"// standardizer:ignore-file 规则2 第三方生成代码", "int a;"},
+			want:  []Suppression{{File: "f", Comment: 1, Scope: SuppressFile, Rules: "规则2", Reason: "第三方生成代码"}},
+		},
+		{
+			name:  "屏蔽整个文件的所有规则",
+			lines: []string{"/* standardizer:ignore-file */"},
+			want:  []Suppression{{File: "f", Comment: 1, Scope: SuppressFile}},
+		},
+		{
+			name:  "块注释",
+			lines: []string{"int *p; /* standardizer:ignore 规则3 */"},
+			want:  []Suppression{{File: "f", Line: 1, Comment: 1, Scope: SuppressLine, Rules: "规则3"}},
+		},
+		{
+			name:  "Python 注释",
+			lines: []string{"# standardizer:ignore 规则4 兼容旧版本", "except:", "x = 1  # standardizer:ignore 规则5"},
+			want: []Suppression{
+				{File: "f", Line: 2, Comment: 1, Scope: SuppressLine, Rules: "规则4", Reason: "兼容旧版本"},
+				{File: "f", Line: 3, Comment: 3, Scope: SuppressLine, Rules: "规则5"},
+			},
+		},
+		{
+			name:  "规则列表格式错误时忽略注释",
+			lines: []string{"int *p; // standardizer:ignore 规则3,foo 原因", "int *q; // standardizer:ignore 规则1,,规则2"},
+			want:  nil,
+		},
+		{
+			name:  "以规则开头的普通说明视为理由",
+			lines: []string{"int *p; // standardizer:ignore 规则太严"},
+			want:  []Suppression{{File: "f", Line: 1, Comment: 1, Scope: SuppressLine, Reason: "规则太严"}},
+		},
+		{
+			name:  "不是屏蔽指令",
+			lines: []string{"// standardizer:ignored 规则1", "// standardizer: ignore 规则1", "int a; // ignore 规则1"},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSuppressions("f", tt.lines)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSuppressions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySuppressions(t *testing.T) {
+	issues := []Issue{
+		{Line: 1, Rule: "规则1"},
+		{Line: 2, Rule: "规则2"},
+		{Line: 2, Rule: "规则3"},
+		{Line: 5, Rule: "规则2"},
+	}
+	tests := []struct {
+		name         string
+		suppressions []Suppression
+		wantLines    []int
+		wantMatched  []int
+	}{
+		{
+			name:         "没有屏蔽注释",
+			suppressions: nil,
+			wantLines:    []int{1, 2, 2, 5},
+		},
+		{
+			name:         "行屏蔽只作用于指定规则",
+			suppressions: []Suppression{{Scope: SuppressLine, Line: 2, Rules: "规则2"}},
+			wantLines:    []int{1, 2, 5},
+			wantMatched:  []int{1},
+		},
+		{
+			name:         "不指定规则时屏蔽该行所有问题",
+			suppressions: []Suppression{{Scope: SuppressLine, Line: 2}},
+			wantLines:    []int{1, 5},
+			wantMatched:  []int{2},
+		},
+		{
+			name:         "文件屏蔽作用于所有行",
+			suppressions: []Suppression{{Scope: SuppressFile, Rules: "规则2"}},
+			wantLines:    []int{1, 2},
+			wantMatched:  []int{2},
+		},
+		{
+			name:         "多条注释匹配同一问题时只计入第一条",
+			suppressions: []Suppression{{Scope: SuppressFile, Rules: "规则1"}, {Scope: SuppressLine, Line: 1}},
+			wantLines:    []int{2, 2, 5},
+			wantMatched:  []int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]Issue(nil), issues...)
+			kept := ApplySuppressions(input, tt.suppressions)
+			var lines []int
+			for _, issue := range kept {
+				lines = append(lines, issue.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("保留的问题行 = %v, want %v", lines, tt.wantLines)
+			}
+			for i, want := range tt.wantMatched {
+				if tt.suppressions[i].Matched != want {
+					t.Errorf("第 %d 条注释命中 %d 次, want %d", i, tt.suppressions[i].Matched, want)
+				}
+			}
+		})
+	}
+}
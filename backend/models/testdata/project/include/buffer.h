@@ -0,0 +1,19 @@
+#pragma once
+
+typedef unsigned int length_t;
+
+namespace io {
+
+// 定长缓冲区
+class Buffer {
+public:
+    Buffer();
+    int Write(const char *data, length_t size);
+    length_t Size() const;
+
+private:
+    char *data_;
+    length_t size_;
+};
+
+}  // namespace io
@@ -0,0 +1,7 @@
+int Checksum(const char *data, length_t size) {
+    int sum = 0;
+    for (length_t i = 0; i < size; i++) {
+        sum += data[i];
+    }
+    return sum;
+}
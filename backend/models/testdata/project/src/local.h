@@ -0,0 +1,4 @@
+struct Header {
+    int magic;
+    using Tag = unsigned char;
+};
@@ -0,0 +1,11 @@
+#include "buffer.h"
+#include "local.h"
+
+static int Parse(Header *h);
+
+int main() {
+    io::Buffer buf;
+    int c = Checksum(nullptr, buf.Size());
+    Header h;
+    return Parse(&h) + c;
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 检查发现状态
+const (
+	FindingOpen          = "open"           // 待处理
+	FindingAccepted      = "accepted"       // 确认为问题
+	FindingFalsePositive = "false_positive" // 误报
+	FindingWontFix       = "wont_fix"       // 不修复
+)
+
+// 合法的检查发现状态
+func IsValidFindingStatus(status string) bool {
+	switch status {
+	case FindingOpen, FindingAccepted, FindingFalsePositive, FindingWontFix:
+		return true
+	}
+	return false
+}
+
+// 误报和不修复的问题不计入统计
+func IsSuppressedStatus(status string) bool {
+	return status == FindingFalsePositive || status == FindingWontFix
+}
+
+// 状态的中文名称
+func FindingStatusLabel(status string) string {
+	switch status {
+	case FindingAccepted:
+		return "已确认"
+	case FindingFalsePositive:
+		return "误报"
+	case FindingWontFix:
+		return "不修复"
+	default:
+		return "待处理"
+	}
+}
+
+// 人工判定记录，按指纹延续到后续扫描
+type FindingTriage struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	FindingID     uint      `json:"finding_id" gorm:"index"`
+	Fingerprint   string    `json:"fingerprint" gorm:"size:40;index"`
+	FromStatus    string    `json:"from_status" gorm:"size:16"`
+	Status        string    `json:"status" gorm:"size:16"`
+	Justification string    `json:"justification" gorm:"type:text"`
+	UserName      string    `json:"username"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// 计算检查发现指纹：文件路径、规则和去除空白后的源码行，行号变化不影响指纹
+func Fingerprint(file, rule, sourceLine string) string {
+	normalized := strings.Join(strings.Fields(sourceLine), " ")
+	sum := sha1.Sum([]byte(filepath.ToSlash(file) + "\x00" + rule + "\x00" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// 将判定结果应用到检查发现
+func (f *Finding) ApplyTriage(t *FindingTriage) {
+	createdAt := t.CreatedAt
+	f.Status = t.Status
+	f.Justification = t.Justification
+	f.TriagedBy = t.UserName
+	f.TriagedAt = &createdAt
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// 验证修复：在修复后的代码上重新运行静态检查器和 LLM，确认问题消失且没有引入新问题
+func (c *CodeAnalyzer) verifyFixes(path string, lines []string, lang *Language, rules []Rule) {
+	c.Mu.Lock()
+	issues := c.Results[path]
+	c.Mu.Unlock()
+
+	verified := 0
+	for i := range issues {
+		fix := issues[i].Fix
+		if fix == nil || !fix.Applicable {
+			continue
+		}
+		if note := c.verifyFix(path, lines, lang, rules, &issues[i], issues); note != "" {
+			fix.Verified, fix.VerifyNote = false, note
+			slog.Debug("修复未通过验证", "file", path, "line", fix.StartLine, "rule", issues[i].Rule, "reason", note)
+			continue
+		}
+		fix.Verified, fix.VerifyNote = true, ""
+		verified++
+	}
+	slog.Debug("修复验证完成", "file", path, "verified_count", verified)
+}
+
+// 验证单个修复，通过时返回空字符串，否则返回原因
+func (c *CodeAnalyzer) verifyFix(path string, lines []string, lang *Language, rules []Rule, issue *Issue, issues []Issue) string {
+	fix := issue.Fix
+	patched := applyFix(lines, fix)
+	// 替换后的代码在修复后文件中的行范围
+	from, to := fix.StartLine, fix.StartLine+len(fix.ReplacementLines())-1
+
+	// 修复前这几行已有的问题，不算作新引入的问题
+	before := make(map[string]bool)
+	for _, is := range issues {
+		if is.Line >= fix.StartLine && is.Line <= fix.EndLine {
+			before[is.Rule] = true
+		}
+	}
+
+	for _, is := range RunStaticCheckers(lang, path, patched, rules) {
+		if is.Line < from || is.Line > to {
+			continue
+		}
+		if is.Rule == issue.Rule {
+			return "静态检查仍发现该问题"
+		}
+		if !before[is.Rule] {
+			return fmt.Sprintf("静态检查发现新问题：%s", is.Rule)
+		}
+	}
+
+	// 将修复所在的代码片段交给 LLM 重新检查
+	start := max(from-fixContextLines, 1)
+	end := min(to+fixContextLines, len(patched))
+	chunk := strings.Join(patched[start-1:end], "\n")
+	// 验证提示不计入本次扫描使用的模板，避免影响提示词版本的统计
+	prompt, _ := c.renderPrompt(chunk, c.projectContext(path, start-1, chunk), rules, lang)
+	response, _, err := c.generate(CallVerify, c.defaultVoter(), prompt, path, nil)
+	if err != nil {
+		slog.Error("LLM验证修复失败", "file", path, "line", fix.StartLine, "error", err)
+		return "LLM验证失败"
+	}
+	// 去掉推理模型的思考过程，其中的推理内容可能与问题格式相同
+	response = thinkRe.ReplaceAllString(response, "")
+	for _, is := range keepRules(parseLLMResponse(response, path, start-1), rules) {
+		if is.Line < from || is.Line > to {
+			continue
+		}
+		if is.Rule == issue.Rule {
+			return "LLM仍报告该问题：" + is.Original
+		}
+		if !before[is.Rule] {
+			return fmt.Sprintf("LLM发现新问题：%s %s", is.Rule, is.Original)
+		}
+	}
+	return ""
+}
+
+// 只应用一个修复，不修改修复的状态
+func applyFix(lines []string, fix *Fix) []string {
+	patched := make([]string, 0, len(lines))
+	patched = append(patched, lines[:fix.StartLine-1]...)
+	patched = append(patched, fix.ReplacementLines()...)
+	return append(patched, lines[fix.EndLine:]...)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerifyFixIgnoresThinking(t *testing.T) {
+	lines := []string{"int main() {", "  double d = (double)1;", "  return 0;", "}"}
+	rules := []Rule{{ID: "规则1", Description: "禁止使用 goto"}, {ID: "规则2", Description: "禁止C风格强制类型转换"}}
+	tests := []struct {
+		name     string
+		response string
+		wantNote bool
+	}{
+		// 思考过程中复述的问题不算作修复后仍存在的问题
+		{name: "思考过程中的问题行", response: "<think>\n2:规则2:原代码使用(double):改用static_cast\n</think>\nOK"},
+		{name: "回答中的问题", response: "<think>\n检查第2行\n</think>\n2:规则2:仍使用C风格转换:改用static_cast", wantNote: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CodeAnalyzer{
+				Llm: &scriptedModel{responses: []string{tt.response}},
+				Ctx: context.Background(),
+			}
+			issue := &Issue{File: "a.cpp", Line: 2, Rule: "规则2"}
+			issue.Fix = NewFix(lines, 2, 2, "  double d = static_cast<double>(1);", FixFromLLM)
+			note := c.verifyFix("a.cpp", lines, LanguageByName("cpp"), rules, issue, []Issue{*issue})
+			if (note != "") != tt.wantNote {
+				t.Errorf("got note %q, want note=%v", note, tt.wantNote)
+			}
+			// 验证提示不计入提示词版本统计
+			if report := c.GenerateReport("."); len(report.Prompts) != 0 {
+				t.Errorf("report prompts %v, want none from verification", report.Prompts)
+			}
+		})
+	}
+}
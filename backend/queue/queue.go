@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"github.com/streadway/amqp"
+)
+
+// 文件扫描任务队列
+const ScanQueue = "file_scan_queue"
+
+// 发布扫描任务消息
+type Publisher interface {
+	Publish(body []byte) error
+}
+
+// 消费扫描任务消息
+type Consumer interface {
+	// 每次预取一条消息，处理后须手动确认，closeFn 关闭本次消费
+	Consume() (msgs <-chan amqp.Delivery, closeFn func() error, err error)
+}
+
+// 基于 RabbitMQ 的扫描任务队列
+type RabbitMQ struct {
+	Conn *amqp.Connection
+}
+
+func Dial(url string) (*RabbitMQ, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RabbitMQ{Conn: conn}, nil
+}
+
+// 打开通道并声明持久化的扫描任务队列
+func (q *RabbitMQ) Channel() (*amqp.Channel, amqp.Queue, error) {
+	ch, err := q.Conn.Channel()
+	if err != nil {
+		return nil, amqp.Queue{}, err
+	}
+	declared, err := ch.QueueDeclare(
+		ScanQueue, // 队列名称
+		true,      // 持久化
+		false,     // 自动删除
+		false,     // 排他
+		false,     // 等待服务器响应
+		nil,       // 额外参数
+	)
+	if err != nil {
+		ch.Close()
+		return nil, amqp.Queue{}, err
+	}
+	return ch, declared, nil
+}
+
+// 在新通道上注册消费者，每次只取一条消息，处理完成后再确认
+func (q *RabbitMQ) Consume() (<-chan amqp.Delivery, func() error, error) {
+	ch, declared, err := q.Channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ch.Qos(1, 0, false); err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+	msgs, err := ch.Consume(
+		declared.Name, // 队列名称
+		"",            // 消费者名称
+		false,         // 手动确认
+		false,         // 排他
+		false,         // 本地
+		false,         // 等待服务器响应
+		nil,           // 额外参数
+	)
+	if err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+	return msgs, ch.Close, nil
+}
+
+// 发布一条持久化消息
+func (q *RabbitMQ) Publish(body []byte) error {
+	ch, declared, err := q.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	return ch.Publish(
+		"",            // 交换器
+		declared.Name, // 路由键
+		false,         // 强制
+		false,         // 立即
+		amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  "application/json",
+			Body:         body,
+		})
+}
+
+func (q *RabbitMQ) Close() error {
+	return q.Conn.Close()
+}
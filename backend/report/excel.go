@@ -0,0 +1,360 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"standardizer/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	summarySheet = "汇总"
+	detailSheet  = "问题明细"
+	ruleSheet    = "规则说明"
+	auditSheet   = "审计"
+	inlineSheet  = "源码屏蔽"
+)
+
+// 写出 Excel 报告，包含汇总、问题明细、规则说明、审计和源码屏蔽工作表。
+// 报告在下载时按数据库中的最新状态生成，人工判定后的问题数和审计记录随之更新
+func WriteExcelReport(w io.Writer, data *models.Report) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", summarySheet); err != nil {
+		return err
+	}
+	for _, sheet := range []string{detailSheet, ruleSheet, auditSheet, inlineSheet} {
+		if _, err := f.NewSheet(sheet); err != nil {
+			return fmt.Errorf("创建 Excel 工作表失败: %w", err)
+		}
+	}
+
+	styles, err := newExcelStyles(f)
+	if err != nil {
+		return err
+	}
+
+	issues := data.ActiveFindings()
+	models.SortFindings(issues)
+
+	firstRowByRule, firstRowByFile, err := writeDetailSheet(f, styles, issues)
+	if err != nil {
+		return err
+	}
+	if err := writeRuleSheet(f, styles, data, firstRowByRule); err != nil {
+		return err
+	}
+	if err := writeSummarySheet(f, styles, data, firstRowByRule, firstRowByFile); err != nil {
+		return err
+	}
+	if err := writeAuditSheet(f, styles, data); err != nil {
+		return err
+	}
+	if err := writeInlineSuppressionSheet(f, styles, data); err != nil {
+		return err
+	}
+	f.SetActiveSheet(0)
+
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("写出 Excel 报告失败: %w", err)
+	}
+	return nil
+}
+
+type excelStyles struct {
+	header  int
+	title   int
+	link    int
+	percent int
+	high    int
+	medium  int
+	low     int
+}
+
+func newExcelStyles(f *excelize.File) (*excelStyles, error) {
+	var s excelStyles
+	var err error
+	if s.header, err = f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"409EFF"}},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	}); err != nil {
+		return nil, err
+	}
+	if s.title, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 14},
+	}); err != nil {
+		return nil, err
+	}
+	if s.link, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "1265BE", Underline: "single"},
+	}); err != nil {
+		return nil, err
+	}
+	if s.percent, err = f.NewStyle(&excelize.Style{NumFmt: 9}); err != nil {
+		return nil, err
+	}
+	// 按严重级别着色的条件格式
+	if s.high, err = f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FDE2E2"}},
+	}); err != nil {
+		return nil, err
+	}
+	if s.medium, err = f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FAECD8"}},
+	}); err != nil {
+		return nil, err
+	}
+	if s.low, err = f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"F4F4F5"}},
+	}); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// 写入表头并冻结首行
+func writeHeader(f *excelize.File, styles *excelStyles, sheet string, headers []string) error {
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return err
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(len(headers), 1)
+	if err := f.SetCellStyle(sheet, "A1", lastCell, styles.header); err != nil {
+		return err
+	}
+	return f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// 写入问题明细，返回每条规则、每个文件首次出现的行号
+func writeDetailSheet(f *excelize.File, styles *excelStyles, issues []models.Finding) (map[string]int, map[string]int, error) {
+	headers := []string{"文件", "行号", "规则", "严重级别", "问题描述", "建议修正", "自动修复", "修复状态", "置信度"}
+	if err := writeHeader(f, styles, detailSheet, headers); err != nil {
+		return nil, nil, err
+	}
+
+	firstRowByRule := make(map[string]int)
+	firstRowByFile := make(map[string]int)
+	for i, issue := range issues {
+		row := i + 2
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		values := []interface{}{issue.File, issue.Line, issue.Rule, models.SeverityLabel(issue.Severity), issue.Original, issue.Suggested, "", models.FixStatusLabel(issue.Fix), issue.Confidence}
+		if issue.Fix != nil && issue.Fix.Applicable {
+			values[6] = issue.Fix.Replacement
+		}
+		if err := f.SetSheetRow(detailSheet, cell, &values); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := firstRowByRule[issue.Rule]; !ok {
+			firstRowByRule[issue.Rule] = row
+		}
+		if _, ok := firstRowByFile[issue.File]; !ok {
+			firstRowByFile[issue.File] = row
+		}
+	}
+
+	lastRow := max(len(issues)+1, 2)
+	if err := f.AutoFilter(detailSheet, fmt.Sprintf("A1:I%d", lastRow), nil); err != nil {
+		return nil, nil, err
+	}
+	for severity, format := range map[string]int{
+		models.SeverityHigh:   styles.high,
+		models.SeverityMedium: styles.medium,
+		models.SeverityLow:    styles.low,
+	} {
+		if err := f.SetConditionalFormat(detailSheet, fmt.Sprintf("A2:I%d", lastRow), []excelize.ConditionalFormatOptions{{
+			Type:     "formula",
+			Criteria: fmt.Sprintf(`$D2="%s"`, models.SeverityLabel(severity)),
+			Format:   &format,
+		}}); err != nil {
+			return nil, nil, err
+		}
+	}
+	f.SetColWidth(detailSheet, "A", "A", 30)
+	f.SetColWidth(detailSheet, "C", "D", 10)
+	f.SetColWidth(detailSheet, "E", "G", 50)
+	f.SetColWidth(detailSheet, "H", "I", 10)
+	if err := f.SetCellStyle(detailSheet, "I2", fmt.Sprintf("I%d", lastRow), styles.percent); err != nil {
+		return nil, nil, err
+	}
+	return firstRowByRule, firstRowByFile, nil
+}
+
+// 写入规则说明
+func writeRuleSheet(f *excelize.File, styles *excelStyles, data *models.Report, firstRowByRule map[string]int) error {
+	headers := []string{"规则", "严重级别", "问题数", "规则描述"}
+	if err := writeHeader(f, styles, ruleSheet, headers); err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, issue := range data.ActiveFindings() {
+		counts[issue.Rule]++
+	}
+	for i, rule := range data.Rules {
+		row := i + 2
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		values := []interface{}{rule.ID, models.SeverityLabel(rule.Severity), counts[rule.ID], rule.Description}
+		if err := f.SetSheetRow(ruleSheet, cell, &values); err != nil {
+			return err
+		}
+		if detailRow, ok := firstRowByRule[rule.ID]; ok {
+			if err := setDetailLink(f, styles, ruleSheet, cell, detailRow); err != nil {
+				return err
+			}
+		}
+	}
+	f.SetColWidth(ruleSheet, "D", "D", 70)
+	return nil
+}
+
+// 写入汇总：按严重级别、规则、文件统计，规则和文件链接到首条明细
+func writeSummarySheet(f *excelize.File, styles *excelStyles, data *models.Report, firstRowByRule, firstRowByFile map[string]int) error {
+	f.SetCellValue(summarySheet, "A1", data.Title)
+	f.SetCellStyle(summarySheet, "A1", "A1", styles.title)
+	overview := [][]interface{}{
+		{"扫描对象", data.FileName},
+		{"检查文件数", data.TotalFiles},
+		{"问题总数", data.TotalIssues},
+		{"已屏蔽问题数", data.Suppressed},
+		{"适用规则数", data.RuleCount},
+	}
+	if data.HasGate() {
+		result := "未通过"
+		if data.Passed {
+			result = "通过"
+		}
+		minSeverity := "不限"
+		if data.MinSeverity != "" {
+			minSeverity = models.SeverityLabel(data.MinSeverity)
+		}
+		overview = append(overview,
+			[]interface{}{"门禁阈值", fmt.Sprintf("严重级别≥%s，置信度≥%.0f%%", minSeverity, data.MinConfidence*100)},
+			[]interface{}{"门禁问题数", data.GateIssues},
+			[]interface{}{"门禁结果", result},
+		)
+	}
+	row := 3
+	for _, values := range overview {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow(summarySheet, cell, &values); err != nil {
+			return err
+		}
+		row++
+	}
+
+	bySeverity := make(map[string]int)
+	byRule := make(map[string]int)
+	byFile := make(map[string]int)
+	for _, issue := range data.ActiveFindings() {
+		bySeverity[issue.Severity]++
+		byRule[issue.Rule]++
+		byFile[issue.File]++
+	}
+
+	row++
+	row, err := writeSummaryTable(f, styles, row, "严重级别", severityBars(bySeverity), nil)
+	if err != nil {
+		return err
+	}
+	row, err = writeSummaryTable(f, styles, row+1, "规则", buildBars(byRule, ""), firstRowByRule)
+	if err != nil {
+		return err
+	}
+	if _, err = writeSummaryTable(f, styles, row+1, "文件", buildBars(byFile, ""), firstRowByFile); err != nil {
+		return err
+	}
+	f.SetColWidth(summarySheet, "A", "A", 40)
+	f.SetColWidth(summarySheet, "B", "B", 12)
+	return nil
+}
+
+// 写入被判定为误报或不修复的问题
+func writeAuditSheet(f *excelize.File, styles *excelStyles, data *models.Report) error {
+	headers := []string{"文件", "行号", "规则", "判定", "理由", "判定人", "判定时间"}
+	if err := writeHeader(f, styles, auditSheet, headers); err != nil {
+		return err
+	}
+	suppressed := data.SuppressedFindings()
+	models.SortFindings(suppressed)
+	for i, finding := range suppressed {
+		triagedAt := ""
+		if finding.TriagedAt != nil {
+			triagedAt = finding.TriagedAt.Format("2006-01-02 15:04")
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		values := []interface{}{finding.File, finding.Line, finding.Rule, models.FindingStatusLabel(finding.Status), finding.Justification, finding.TriagedBy, triagedAt}
+		if err := f.SetSheetRow(auditSheet, cell, &values); err != nil {
+			return err
+		}
+	}
+	f.SetColWidth(auditSheet, "A", "A", 30)
+	f.SetColWidth(auditSheet, "E", "E", 50)
+	f.SetColWidth(auditSheet, "G", "G", 18)
+	return nil
+}
+
+// 写入源码中的屏蔽注释
+func writeInlineSuppressionSheet(f *excelize.File, styles *excelStyles, data *models.Report) error {
+	headers := []string{"文件", "注释行", "屏蔽范围", "规则", "理由", "屏蔽问题数"}
+	if err := writeHeader(f, styles, inlineSheet, headers); err != nil {
+		return err
+	}
+	for i, s := range data.Suppressions {
+		scope := "整个文件"
+		if s.Scope == models.SuppressLine {
+			scope = fmt.Sprintf("第 %d 行", s.Line)
+		}
+		rules := s.Rules
+		if rules == "" {
+			rules = "全部规则"
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		values := []interface{}{s.File, s.Comment, scope, rules, s.Reason, s.Matched}
+		if err := f.SetSheetRow(inlineSheet, cell, &values); err != nil {
+			return err
+		}
+	}
+	f.SetColWidth(inlineSheet, "A", "A", 30)
+	f.SetColWidth(inlineSheet, "E", "E", 50)
+	return nil
+}
+
+// 写入一张统计表，返回下一个空行
+func writeSummaryTable(f *excelize.File, styles *excelStyles, row int, title string, bars []Bar, links map[string]int) (int, error) {
+	headerCell, _ := excelize.CoordinatesToCellName(1, row)
+	endCell, _ := excelize.CoordinatesToCellName(2, row)
+	if err := f.SetSheetRow(summarySheet, headerCell, &[]string{title, "问题数"}); err != nil {
+		return row, err
+	}
+	if err := f.SetCellStyle(summarySheet, headerCell, endCell, styles.header); err != nil {
+		return row, err
+	}
+	row++
+	for _, bar := range bars {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow(summarySheet, cell, &[]interface{}{bar.Label, bar.Count}); err != nil {
+			return row, err
+		}
+		if detailRow, ok := links[bar.Label]; ok {
+			if err := setDetailLink(f, styles, summarySheet, cell, detailRow); err != nil {
+				return row, err
+			}
+		}
+		row++
+	}
+	return row, nil
+}
+
+// 设置跳转到明细行的超链接
+func setDetailLink(f *excelize.File, styles *excelStyles, sheet, cell string, detailRow int) error {
+	location := fmt.Sprintf("'%s'!A%d", detailSheet, detailRow)
+	if err := f.SetCellHyperLink(sheet, cell, location, "Location"); err != nil {
+		return err
+	}
+	return f.SetCellStyle(sheet, cell, cell, styles.link)
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteExcelReport(t *testing.T) {
+	data := loadTestReport(t)
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteExcelReport(out, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	wantSheets := []string{summarySheet, detailSheet, ruleSheet, auditSheet, inlineSheet}
+	if got := f.GetSheetList(); !reflect.DeepEqual(got, wantSheets) {
+		t.Fatalf("sheets %v, want %v", got, wantSheets)
+	}
+
+	rows := make(map[string][][]string)
+	for _, sheet := range wantSheets {
+		if rows[sheet], err = f.GetRows(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 表头加数据行：两个未屏蔽的问题、三条规则、一个人工判定、一条屏蔽注释
+	for sheet, want := range map[string]int{detailSheet: 3, ruleSheet: 4, auditSheet: 2, inlineSheet: 2} {
+		if got := len(rows[sheet]); got != want {
+			t.Errorf("%s has %d rows, want %d", sheet, got, want)
+		}
+	}
+
+	overview := make(map[string]string)
+	for _, row := range rows[summarySheet] {
+		if len(row) == 2 {
+			overview[row[0]] = row[1]
+		}
+	}
+	if overview["问题总数"] != "2" || overview["已屏蔽问题数"] != "1" || overview["适用规则数"] != "3" {
+		t.Errorf("summary overview %v", overview)
+	}
+
+	detail := rows[detailSheet]
+	if detail[1][2] != "规则1" || detail[1][6] != "  int *p = nullptr;" || detail[1][7] != "已验证" || detail[2][2] != "规则2" {
+		t.Errorf("detail rows %q", detail[1:])
+	}
+	if want := []string{data.Files[0].File, "4", "规则3", "误报", "错误处理统一出口", "张工", "2026-10-01 09:30"}; !reflect.DeepEqual(rows[auditSheet][1], want) {
+		t.Errorf("audit row %q, want %q", rows[auditSheet][1], want)
+	}
+	if want := []string{data.Files[0].File, "5", "第 6 行", "规则4", "历史代码", "1"}; !reflect.DeepEqual(rows[inlineSheet][1], want) {
+		t.Errorf("inline suppression row %q, want %q", rows[inlineSheet][1], want)
+	}
+}
@@ -0,0 +1,198 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"standardizer/models"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var htmlTemplate = template.Must(template.New("report.html.tmpl").Funcs(template.FuncMap{
+	"severityLabel": models.SeverityLabel,
+	"statusLabel":   models.FindingStatusLabel,
+	"fixStatus":     models.FixStatusLabel,
+	"confidence":    formatConfidence,
+	"formatTime": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04")
+	},
+}).ParseFS(templateFS, "templates/report.html.tmpl"))
+
+// 置信度百分比，没有投票信息时为空
+func formatConfidence(confidence float64) string {
+	if confidence <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%%", confidence*100)
+}
+
+// 源码片段前后保留的行数
+const snippetContext = 3
+
+// 统计图中的一根柱
+type Bar struct {
+	Label   string
+	Count   int
+	Percent int
+	Class   string
+}
+
+type SnippetLine struct {
+	Number    int
+	Text      string
+	Highlight bool
+}
+
+type IssueView struct {
+	models.Finding
+	Snippet []SnippetLine
+}
+
+type FileSection struct {
+	File   string
+	Issues []IssueView
+}
+
+type htmlView struct {
+	*models.Report
+	GeneratedAt string
+	ByRule      []Bar
+	BySeverity  []Bar
+	ByFile      []Bar
+	Sections    []FileSection
+	Audit       []models.Finding
+}
+
+// 渲染 HTML 报告
+func RenderHTML(w io.Writer, data *models.Report) error {
+	view := htmlView{
+		Report:      data,
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	byRule := make(map[string]int)
+	bySeverity := make(map[string]int)
+	byFile := make(map[string]int)
+	sections := make(map[string]*FileSection)
+	var fileOrder []string
+	for _, issue := range data.ActiveFindings() {
+		byRule[issue.Rule]++
+		bySeverity[issue.Severity]++
+		byFile[issue.File]++
+		section, ok := sections[issue.File]
+		if !ok {
+			section = &FileSection{File: issue.File}
+			sections[issue.File] = section
+			fileOrder = append(fileOrder, issue.File)
+		}
+		section.Issues = append(section.Issues, IssueView{Finding: issue})
+	}
+
+	view.ByRule = buildBars(byRule, "rule")
+	view.ByFile = buildBars(byFile, "file")
+	view.BySeverity = severityBars(bySeverity)
+
+	sort.Strings(fileOrder)
+	for _, file := range fileOrder {
+		section := sections[file]
+		sort.SliceStable(section.Issues, func(i, j int) bool {
+			return section.Issues[i].Line < section.Issues[j].Line
+		})
+		lines := readSourceLines(file)
+		for i := range section.Issues {
+			section.Issues[i].Snippet = buildSnippet(lines, section.Issues[i].Line)
+		}
+		view.Sections = append(view.Sections, *section)
+	}
+
+	// 被判定为误报或不修复的问题单独列在审计部分
+	view.Audit = data.SuppressedFindings()
+	models.SortFindings(view.Audit)
+
+	return htmlTemplate.Execute(w, view)
+}
+
+// 按数量降序生成柱状图数据
+func buildBars(counts map[string]int, class string) []Bar {
+	top := 0
+	for _, count := range counts {
+		top = max(top, count)
+	}
+	bars := make([]Bar, 0, len(counts))
+	for label, count := range counts {
+		bars = append(bars, Bar{Label: label, Count: count, Percent: percent(count, top), Class: class})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Count != bars[j].Count {
+			return bars[i].Count > bars[j].Count
+		}
+		return bars[i].Label < bars[j].Label
+	})
+	return bars
+}
+
+// 按严重级别从高到低排列
+func severityBars(counts map[string]int) []Bar {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	var bars []Bar
+	for _, severity := range []string{models.SeverityHigh, models.SeverityMedium, models.SeverityLow} {
+		if count := counts[severity]; count > 0 {
+			bars = append(bars, Bar{
+				Label:   models.SeverityLabel(severity),
+				Count:   count,
+				Percent: percent(count, total),
+				Class:   severity,
+			})
+		}
+	}
+	return bars
+}
+
+func percent(count, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return count * 100 / total
+}
+
+// 读取源文件，失败时报告中不展示源码片段
+func readSourceLines(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("读取源文件失败，报告中不展示源码", "file", path, "error", err)
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// 截取问题行前后的源码
+func buildSnippet(lines []string, line int) []SnippetLine {
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	start := max(line-snippetContext, 1)
+	end := min(line+snippetContext, len(lines))
+	snippet := make([]SnippetLine, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		snippet = append(snippet, SnippetLine{
+			Number:    n,
+			Text:      lines[n-1],
+			Highlight: n == line,
+		})
+	}
+	return snippet
+}
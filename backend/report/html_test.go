@@ -0,0 +1,117 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"standardizer/migrations"
+	"standardizer/models"
+	"standardizer/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 一份包含检查发现、自动修复、人工判定和源码屏蔽注释的报告，
+// 保存到内存数据库后重新读取，与下载报告时的数据一致
+func loadTestReport(t *testing.T) *models.Report {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "main.cpp")
+	code := "int main() {\n  int *p;\n  double d = (double)1;\n  goto end;\n  // standardizer:ignore 规则4 历史代码\n  goto end;\nend:\n  return 0;\n}\n"
+	if err := os.WriteFile(source, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	triagedAt := time.Date(2026, 10, 1, 9, 30, 0, 0, time.Local)
+	data := &models.Report{
+		SchemaVersion: models.ReportSchemaVersion,
+		MD5Low32:      "html",
+		FileName:      "main",
+		Title:         "代码规范检查报告",
+		RuleCount:     3,
+		Rules: []models.Rule{
+			{ID: "规则1", Description: "指针声明时必须初始化", Severity: models.SeverityHigh},
+			{ID: "规则2", Description: "禁止C风格强制类型转换", Severity: models.SeverityMedium},
+			{ID: "规则3", Description: "禁止使用 goto", Severity: models.SeverityLow},
+		},
+		Files: []models.FileResult{{File: source}},
+		Findings: []models.Finding{
+			{File: source, Line: 2, Rule: "规则1", Severity: models.SeverityHigh, Status: models.FindingOpen, Original: "指针未初始化", Suggested: "int *p = nullptr;", Confidence: 1,
+				Fix: &models.Fix{StartLine: 2, EndLine: 2, Original: "  int *p;", Replacement: "  int *p = nullptr;", Applicable: true, Verified: true}},
+			{File: source, Line: 3, Rule: "规则2", Severity: models.SeverityMedium, Status: models.FindingAccepted, Original: "C风格转换", Suggested: "static_cast<double>(1)", Confidence: 2.0 / 3},
+			{File: source, Line: 4, Rule: "规则3", Severity: models.SeverityLow, Status: models.FindingFalsePositive, Original: "使用 goto", Confidence: 1,
+				Justification: "错误处理统一出口", TriagedBy: "张工", TriagedAt: &triagedAt},
+		},
+		Suppressions: []models.Suppression{
+			{File: source, Line: 6, Comment: 5, Scope: models.SuppressLine, Rules: "规则4", Reason: "历史代码", Matched: 1},
+		},
+	}
+	data.Recount()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	reports := &repository.GormReports{DB: db}
+	if err := reports.Create(data); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := reports.Get(data.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestRenderHTML(t *testing.T) {
+	data := loadTestReport(t)
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<title>代码规范检查报告 - main</title>",
+		`<div>发现问题</div><div class="value">2</div>`,
+		`<div>已屏蔽</div><div class="value">1</div>`,
+		"<h2>问题统计</h2>",
+		"<h2>规则说明</h2>",
+		"规则1：指针声明时必须初始化",
+		"<h2>" + data.Files[0].File + "</h2>",
+		// 源码片段高亮问题行
+		`<span class="hl"><span class="ln">    2 </span>  int *p;</span>`,
+		// 自动修复
+		`<span class="fix verified">已验证</span>`,
+		`<pre class="fix-code">  int *p = nullptr;</pre>`,
+		// 人工确认的问题及投票置信度
+		`<span class="status">已确认</span>`,
+		`置信度 67%`,
+		// 源码屏蔽注释
+		"<h2>审计：源码中的屏蔽注释</h2>",
+		"<td>5</td><td>第 6 行</td><td>规则4</td><td>历史代码</td><td>1</td>",
+		// 人工判定屏蔽的问题
+		"<h2>审计：人工判定屏蔽的问题</h2>",
+		"<td>4</td><td>规则3</td><td>误报</td><td>错误处理统一出口</td><td>张工</td><td>2026-10-01 09:30</td>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered report lacks %q", want)
+		}
+	}
+	// 被屏蔽的问题只出现在审计部分
+	if strings.Contains(html, "问题描述：使用 goto") {
+		t.Error("suppressed finding rendered among active issues")
+	}
+}
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"standardizer/models"
+	"strings"
+)
+
+// 补丁中修复前后保留的上下文行数
+const patchContext = 3
+
+// 一个文件的修复：Name 为补丁中使用的相对路径，Path 为源文件路径
+type FilePatch struct {
+	Name  string
+	Path  string
+	Fixes []*models.Fix
+}
+
+// 源文件内容，去掉末尾换行产生的空行
+type sourceFile struct {
+	lines        []string
+	finalNewline bool
+}
+
+func readSource(path string) (*sourceFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := &sourceFile{lines: strings.Split(string(content), "\n")}
+	if n := len(src.lines); n > 0 && src.lines[n-1] == "" {
+		src.lines = src.lines[:n-1]
+		src.finalNewline = true
+	}
+	return src, nil
+}
+
+// 将修复应用到源文件，返回修复后的内容和实际应用的修复
+func FixedFile(p FilePatch) ([]byte, []*models.Fix, error) {
+	src, err := readSource(p.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fixed, applied := models.ApplyFixes(src.lines, p.Fixes)
+	content := strings.Join(fixed, "\n")
+	if src.finalNewline {
+		content += "\n"
+	}
+	return []byte(content), applied, nil
+}
+
+// 以统一 diff 格式写出多个文件的修复补丁，可用 git apply 或 patch -p1 应用
+func WritePatch(w io.Writer, patches []FilePatch) error {
+	for _, p := range patches {
+		src, err := readSource(p.Path)
+		if err != nil {
+			return err
+		}
+		_, applied := models.ApplyFixes(src.lines, p.Fixes)
+		if len(applied) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "--- a/%s\n+++ b/%s\n", p.Name, p.Name); err != nil {
+			return err
+		}
+		if err := writeHunks(w, src, applied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 相邻的修复上下文重叠时合并为一个 hunk
+func writeHunks(w io.Writer, src *sourceFile, fixes []*models.Fix) error {
+	offset := 0 // 之前的修复造成的行数变化
+	for i := 0; i < len(fixes); {
+		j := i + 1
+		for j < len(fixes) && fixes[j].StartLine-fixes[j-1].EndLine <= 2*patchContext+1 {
+			j++
+		}
+		group := fixes[i:j]
+
+		oldStart := max(group[0].StartLine-patchContext, 1)
+		oldEnd := min(group[len(group)-1].EndLine+patchContext, len(src.lines))
+		var body []string
+		next := oldStart
+		newCount := 0
+		for _, fix := range group {
+			for ; next < fix.StartLine; next++ {
+				body = append(body, " "+src.lines[next-1])
+				newCount++
+			}
+			for n := fix.StartLine; n <= fix.EndLine; n++ {
+				body = append(body, "-"+src.lines[n-1])
+			}
+			if fix.EndLine == len(src.lines) && !src.finalNewline {
+				body = append(body, `\ No newline at end of file`)
+			}
+			for _, line := range fix.ReplacementLines() {
+				body = append(body, "+"+line)
+				newCount++
+			}
+			if fix.EndLine == len(src.lines) && !src.finalNewline {
+				body = append(body, `\ No newline at end of file`)
+			}
+			next = fix.EndLine + 1
+		}
+		for ; next <= oldEnd; next++ {
+			body = append(body, " "+src.lines[next-1])
+			newCount++
+		}
+		if oldEnd == len(src.lines) && next-1 > group[len(group)-1].EndLine && !src.finalNewline {
+			body = append(body, `\ No newline at end of file`)
+		}
+
+		oldCount := oldEnd - oldStart + 1
+		if _, err := fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n%s\n",
+			oldStart, oldCount, oldStart+offset, newCount, strings.Join(body, "\n")); err != nil {
+			return err
+		}
+		offset += newCount - oldCount
+		i = j
+	}
+	return nil
+}
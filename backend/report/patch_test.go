@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"standardizer/models"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新 testdata 中的期望补丁")
+
+// 十行的源文件
+const tenLines = "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\n"
+
+// 一个修复：替换 start 到 end 行
+type fixSpec struct {
+	start, end  int
+	replacement string
+}
+
+// 一个补丁文件
+type fileSpec struct {
+	name    string
+	content string
+	fixes   []fixSpec
+}
+
+func TestWritePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []fileSpec
+	}{
+		{"single", []fileSpec{{"src/a.cpp", tenLines, []fixSpec{{5, 5, "LINE5"}}}}},
+		{"merged_hunk", []fileSpec{{"src/a.cpp", tenLines, []fixSpec{{3, 3, "LINE3"}, {8, 8, "LINE8"}}}}},
+		{"separate_hunks", []fileSpec{{"src/a.cpp", tenLines + "line11\nline12\nline13\nline14\nline15\nline16\nline17\nline18\nline19\nline20\n",
+			[]fixSpec{{2, 2, "LINE2a\nLINE2b"}, {18, 19, "LINE18"}}}}},
+		{"overlapping", []fileSpec{{"src/a.cpp", tenLines, []fixSpec{{4, 6, "MERGED"}, {5, 5, "LINE5"}, {6, 7, "LINE6"}}}}},
+		{"first_line", []fileSpec{{"src/a.cpp", tenLines, []fixSpec{{1, 1, "LINE1"}}}}},
+		{"last_line", []fileSpec{{"src/a.cpp", tenLines, []fixSpec{{10, 10, "LINE10"}}}}},
+		{"last_line_no_newline", []fileSpec{{"src/a.cpp", strings.TrimSuffix(tenLines, "\n"), []fixSpec{{10, 10, "LINE10"}}}}},
+		{"near_end_no_newline", []fileSpec{{"src/a.cpp", strings.TrimSuffix(tenLines, "\n"), []fixSpec{{8, 8, "LINE8"}}}}},
+		{"multi_file", []fileSpec{
+			{"src/a.cpp", tenLines, []fixSpec{{2, 2, "LINE2"}}},
+			{"src/unchanged.cpp", tenLines, []fixSpec{{3, 3, "line3"}}}, // 修复与原始代码相同，不可应用
+			{"include/b.h", "int *p;\n", []fixSpec{{1, 1, "int *p = nullptr;"}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var patches []FilePatch
+			for _, f := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(f.name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				lines := strings.Split(strings.TrimSuffix(f.content, "\n"), "\n")
+				p := FilePatch{Name: f.name, Path: path}
+				for _, spec := range f.fixes {
+					fix := models.NewFix(lines, spec.start, spec.end, spec.replacement, models.FixFromStatic)
+					if fix == nil {
+						t.Fatalf("无效的修复范围 %d-%d", spec.start, spec.end)
+					}
+					p.Fixes = append(p.Fixes, fix)
+				}
+				patches = append(patches, p)
+			}
+
+			var buf bytes.Buffer
+			if err := WritePatch(&buf, patches); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", tt.name+".patch")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != string(want) {
+				t.Errorf("补丁与 %s 不一致:\n%s", golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestFixedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fixes   []fixSpec
+		want    string
+		applied int
+	}{
+		{"保留末尾换行", tenLines, []fixSpec{{10, 10, "LINE10"}}, strings.Replace(tenLines, "line10\n", "LINE10\n", 1), 1},
+		{"没有末尾换行", "a\nb", []fixSpec{{2, 2, "B"}}, "a\nB", 1},
+		{"替换为多行", "a\nb\nc\n", []fixSpec{{2, 2, "b1\nb2\n"}}, "a\nb1\nb2\nc\n", 1},
+		{"重叠的修复只应用第一个", "a\nb\nc\nd\n", []fixSpec{{3, 3, "C"}, {2, 3, "BC"}}, "a\nBC\nd\n", 1},
+		{"不可应用的修复被跳过", "a\nb\n", []fixSpec{{1, 1, "a"}, {2, 2, "B"}}, "a\nB\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "f.c")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(tt.content, "\n"), "\n")
+			p := FilePatch{Name: "f.c", Path: path}
+			for _, spec := range tt.fixes {
+				p.Fixes = append(p.Fixes, models.NewFix(lines, spec.start, spec.end, spec.replacement, models.FixFromLLM))
+			}
+			content, applied, err := FixedFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("content = %q, want %q", content, tt.want)
+			}
+			if len(applied) != tt.applied {
+				t.Errorf("applied %d fixes, want %d", len(applied), tt.applied)
+			}
+		})
+	}
+}
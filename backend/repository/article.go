@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+type GormArticles struct {
+	DB *gorm.DB
+}
+
+func (r *GormArticles) Create(article *models.Article) error {
+	return r.DB.Create(article).Error
+}
+
+func (r *GormArticles) List() ([]models.Article, error) {
+	var articles []models.Article
+	err := r.DB.Find(&articles).Error
+	return articles, err
+}
+
+func (r *GormArticles) Get(id uint) (*models.Article, error) {
+	var article models.Article
+	if err := r.DB.Where("id = ?", id).First(&article).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &article, nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+type GormExchangeRates struct {
+	DB *gorm.DB
+}
+
+func (r *GormExchangeRates) Create(rate *models.ExchangeRate) error {
+	return r.DB.Create(rate).Error
+}
+
+func (r *GormExchangeRates) List() ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	err := r.DB.Find(&rates).Error
+	return rates, err
+}
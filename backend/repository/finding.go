@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"standardizer/models"
+	"standardizer/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 分页游标，记录上一页最后一条检查发现的排序字段
+type FindingCursor struct {
+	File string `json:"f"`
+	Line int    `json:"l"`
+	Rank int    `json:"r"`
+	ID   uint   `json:"i"`
+}
+
+// 检查发现查询条件，字段为空时不过滤
+type FindingQuery struct {
+	ReportID      uint
+	File          string // 通配符匹配路径结尾，如 src/*.cpp
+	Rules         []string
+	Severities    []string
+	Statuses      []string
+	Engines       []string
+	MinSeverity   string
+	MinConfidence float64
+	Text          string         // 在原代码、建议和文件路径中搜索
+	Sort          string         // file、line 或 severity，为空时按 file 排序
+	Desc          bool           // 倒序
+	After         *FindingCursor // 从游标之后开始
+	Limit         int
+}
+
+// 排序字段
+type sortKey struct {
+	column string
+	desc   bool
+	value  func(FindingCursor) interface{}
+}
+
+var (
+	fileKey = sortKey{column: "file", value: func(c FindingCursor) interface{} { return c.File }}
+	lineKey = sortKey{column: "line", value: func(c FindingCursor) interface{} { return c.Line }}
+	rankKey = sortKey{column: "severity_rank", desc: true, value: func(c FindingCursor) interface{} { return c.Rank }}
+	idKey   = sortKey{column: "id", value: func(c FindingCursor) interface{} { return c.ID }}
+)
+
+// 支持的排序方式，末尾均以 id 保证顺序唯一
+var findingSorts = map[string][]sortKey{
+	"file":     {fileKey, lineKey, idKey},
+	"line":     {lineKey, fileKey, idKey},
+	"severity": {rankKey, fileKey, lineKey, idKey},
+}
+
+// 是否为支持的排序方式
+func ValidFindingSort(name string) bool {
+	_, ok := findingSorts[name]
+	return ok
+}
+
+type GormFindings struct {
+	DB *gorm.DB
+}
+
+func (r *GormFindings) Get(id uint) (*models.Finding, error) {
+	var finding models.Finding
+	if err := r.DB.Where("id = ?", id).First(&finding).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &finding, nil
+}
+
+func (r *GormFindings) ListByReport(reportID uint) ([]models.Finding, error) {
+	var findings []models.Finding
+	err := r.DB.Preload("Fix").Where("report_id = ?", reportID).
+		Order("file").Order("line").Find(&findings).Error
+	return findings, err
+}
+
+func (r *GormFindings) Find(q FindingQuery) ([]models.Finding, int64, error) {
+	sortName := q.Sort
+	if sortName == "" {
+		sortName = "file"
+	}
+	keys := findingSorts[sortName]
+	if q.Desc {
+		reversed := make([]sortKey, len(keys))
+		for i, key := range keys {
+			key.desc = !key.desc
+			reversed[i] = key
+		}
+		keys = reversed
+	}
+
+	query := filterFindings(r.DB.Model(&models.Finding{}).Where("report_id = ?", q.ReportID), q).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.After != nil {
+		query = afterCursor(query, keys, *q.After)
+	}
+	for _, key := range keys {
+		if key.desc {
+			query = query.Order(key.column + " desc")
+		} else {
+			query = query.Order(key.column)
+		}
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var findings []models.Finding
+	if err := query.Preload("Fix").Find(&findings).Error; err != nil {
+		return nil, 0, err
+	}
+	return findings, total, nil
+}
+
+// 按查询条件过滤检查发现
+func filterFindings(query *gorm.DB, q FindingQuery) *gorm.DB {
+	if q.File != "" {
+		query = query.Where("file LIKE ? ESCAPE '"+utils.LikeEscape+"'", "%"+utils.GlobToLike(q.File))
+	}
+	if len(q.Rules) > 0 {
+		query = query.Where("rule IN ?", q.Rules)
+	}
+	if len(q.Severities) > 0 {
+		query = query.Where("severity IN ?", q.Severities)
+	}
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+	if len(q.Engines) > 0 {
+		query = query.Where("engine IN ?", q.Engines)
+	}
+	if q.MinSeverity != "" {
+		query = query.Where("severity_rank >= ?", models.SeverityRank(q.MinSeverity))
+	}
+	if q.MinConfidence > 0 {
+		query = query.Where("confidence >= ?", q.MinConfidence)
+	}
+	if text := strings.TrimSpace(q.Text); text != "" {
+		pattern := "%" + utils.EscapeLike(text) + "%"
+		escape := " ESCAPE '" + utils.LikeEscape + "'"
+		query = query.Where("(original LIKE ?"+escape+" OR suggested LIKE ?"+escape+" OR file LIKE ?"+escape+")", pattern, pattern, pattern)
+	}
+	return query
+}
+
+// 拼接游标之后的条件：(k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func afterCursor(query *gorm.DB, keys []sortKey, cursor FindingCursor) *gorm.DB {
+	var clauses []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for _, prev := range keys[:i] {
+			parts = append(parts, prev.column+" = ?")
+			args = append(args, prev.value(cursor))
+		}
+		op := " > ?"
+		if key.desc {
+			op = " < ?"
+		}
+		parts = append(parts, key.column+op)
+		args = append(args, key.value(cursor))
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return query.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+func (r *GormFindings) CountGate(reportID uint, minSeverity string, minConfidence float64) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Finding{}).
+		Where("report_id = ? AND status NOT IN ?", reportID, []string{models.FindingFalsePositive, models.FindingWontFix}).
+		Where("severity_rank >= ? AND confidence >= ?", models.SeverityRank(minSeverity), minConfidence).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *GormFindings) Triage(id uint, triage *models.FindingTriage) (*models.Finding, error) {
+	var finding models.Finding
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&finding).Error; err != nil {
+			return err
+		}
+		if finding.Status == triage.Status {
+			return ErrSameStatus
+		}
+
+		triage.FindingID = finding.ID
+		triage.Fingerprint = finding.Fingerprint
+		triage.FromStatus = finding.Status
+		if err := tx.Create(triage).Error; err != nil {
+			return err
+		}
+		finding.ApplyTriage(triage)
+		if err := tx.Model(&finding).Select("status", "justification", "triaged_by", "triaged_at").Updates(&finding).Error; err != nil {
+			return err
+		}
+
+		// 重新统计报告中的问题数
+		var report models.Report
+		if err := tx.Preload("Findings", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "report_id", "file", "status", "severity", "confidence")
+		}).First(&report, finding.ReportID).Error; err != nil {
+			return err
+		}
+		report.Recount()
+		return tx.Model(&report).Select("total_issues", "suppressed", "files", "gate_issues", "passed").Updates(&report).Error
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &finding, nil
+}
+
+func (r *GormFindings) TriageHistory(finding *models.Finding) ([]models.FindingTriage, error) {
+	query := r.DB.Where("finding_id = ?", finding.ID)
+	if finding.Fingerprint != "" {
+		query = query.Or("fingerprint = ?", finding.Fingerprint)
+	}
+	var history []models.FindingTriage
+	err := query.Order("id desc").Find(&history).Error
+	return history, err
+}
+
+func (r *GormFindings) LatestTriages(fingerprints []string) (map[string]*models.FindingTriage, error) {
+	latest := make(map[string]*models.FindingTriage)
+	if len(fingerprints) == 0 {
+		return latest, nil
+	}
+	var triages []models.FindingTriage
+	if err := r.DB.Where("fingerprint IN ?", fingerprints).Order("id").Find(&triages).Error; err != nil {
+		return nil, err
+	}
+	// 同一指纹以最近一次判定为准
+	for i := range triages {
+		latest[triages[i].Fingerprint] = &triages[i]
+	}
+	return latest, nil
+}
+
+// 提示词模板版本的问题统计
+type PromptStats struct {
+	Prompt        string   `json:"prompt"`
+	Reports       int      `json:"reports"`
+	Findings      int      `json:"findings"`
+	Accepted      int      `json:"accepted"`
+	FalsePositive int      `json:"false_positive"`
+	WontFix       int      `json:"wont_fix"`
+	Precision     *float64 `json:"precision"` // 已判定问题中确认的比例，没有判定时为空
+}
+
+func (r *GormFindings) PromptStats(ruleSet string, rules []string) ([]PromptStats, error) {
+	query := r.DB.Table("findings").
+		Select(`findings.prompt AS prompt,
+			COUNT(DISTINCT findings.report_id) AS reports,
+			COUNT(*) AS findings,
+			SUM(CASE WHEN findings.status = ? THEN 1 ELSE 0 END) AS accepted,
+			SUM(CASE WHEN findings.status = ? THEN 1 ELSE 0 END) AS false_positive,
+			SUM(CASE WHEN findings.status = ? THEN 1 ELSE 0 END) AS wont_fix`,
+			models.FindingAccepted, models.FindingFalsePositive, models.FindingWontFix).
+		Where("findings.engine = ? AND findings.prompt <> ''", models.EngineLLM).
+		Group("findings.prompt").
+		Order("findings.prompt")
+	if ruleSet != "" {
+		query = query.Joins("JOIN reports ON reports.id = findings.report_id").Where("reports.rule_set = ?", ruleSet)
+	}
+	if len(rules) > 0 {
+		query = query.Where("findings.rule IN ?", rules)
+	}
+
+	var stats []PromptStats
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if judged := stats[i].Accepted + stats[i].FalsePositive; judged > 0 {
+			precision := float64(stats[i].Accepted) / float64(judged)
+			stats[i].Precision = &precision
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"standardizer/migrations"
+	"standardizer/models"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 内存中的 SQLite 数据库，已执行全部迁移
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// 保存一份报告，检查发现在文件、行号和严重级别上大量重复，用于检验分页的稳定性
+func createTiedReport(t *testing.T, db *gorm.DB) *models.Report {
+	t.Helper()
+	report := &models.Report{SchemaVersion: models.ReportSchemaVersion, MD5Low32: "tied"}
+	for _, file := range []string{"src/b.cpp", "src/a.cpp"} {
+		for _, line := range []int{2, 1} {
+			for _, severity := range []string{models.SeverityLow, models.SeverityHigh, models.SeverityMedium} {
+				for i := 0; i < 2; i++ {
+					report.Findings = append(report.Findings, models.Finding{
+						File:       file,
+						Line:       line,
+						Rule:       "规则1",
+						Severity:   severity,
+						Rank:       models.SeverityRank(severity),
+						Status:     models.FindingOpen,
+						Confidence: 1,
+					})
+				}
+			}
+		}
+	}
+	if err := (&GormReports{DB: db}).Create(report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// 按排序字段在内存中排序，作为分页结果的期望值
+func sortFindings(findings []models.Finding, sortName string, desc bool) []models.Finding {
+	sorted := append([]models.Finding(nil), findings...)
+	keys := findingSorts[sortName]
+	compare := func(key sortKey, a, b *models.Finding) int {
+		var c int
+		switch key.column {
+		case "file":
+			c = compareValues(a.File < b.File, a.File > b.File)
+		case "line":
+			c = compareValues(a.Line < b.Line, a.Line > b.Line)
+		case "severity_rank":
+			c = compareValues(a.Rank < b.Rank, a.Rank > b.Rank)
+		case "id":
+			c = compareValues(a.ID < b.ID, a.ID > b.ID)
+		}
+		if key.desc != desc {
+			c = -c
+		}
+		return c
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		for _, key := range keys {
+			if c := compare(key, &sorted[i], &sorted[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return sorted
+}
+
+func compareValues(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func TestFindPagesThroughTies(t *testing.T) {
+	db := newTestDB(t)
+	report := createTiedReport(t, db)
+	repo := &GormFindings{DB: db}
+
+	for _, sortName := range []string{"file", "line", "severity"} {
+		for _, desc := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/desc=%v", sortName, desc), func(t *testing.T) {
+				want := sortFindings(report.Findings, sortName, desc)
+
+				var got []models.Finding
+				query := FindingQuery{ReportID: report.ID, Sort: sortName, Desc: desc, Limit: 5}
+				for page := 0; ; page++ {
+					if page > len(want) {
+						t.Fatal("分页没有结束")
+					}
+					findings, total, err := repo.Find(query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if total != int64(len(want)) {
+						t.Fatalf("total = %d, want %d", total, len(want))
+					}
+					got = append(got, findings...)
+					if len(findings) < query.Limit {
+						break
+					}
+					last := findings[len(findings)-1]
+					query.After = &FindingCursor{File: last.File, Line: last.Line, Rank: last.Rank, ID: last.ID}
+				}
+
+				if len(got) != len(want) {
+					t.Fatalf("got %d findings, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if got[i].ID != want[i].ID {
+						t.Fatalf("第 %d 条为 %d (%s:%d %s)，期望 %d (%s:%d %s)", i,
+							got[i].ID, got[i].File, got[i].Line, got[i].Severity,
+							want[i].ID, want[i].File, want[i].Line, want[i].Severity)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFindFilters(t *testing.T) {
+	db := newTestDB(t)
+	report := createTiedReport(t, db)
+	repo := &GormFindings{DB: db}
+
+	tests := []struct {
+		name  string
+		query FindingQuery
+		want  int64
+	}{
+		{"全部", FindingQuery{}, 24},
+		{"文件通配符", FindingQuery{File: "src/a.*"}, 12},
+		{"通配符匹配路径结尾", FindingQuery{File: "*.cpp"}, 24},
+		{"下划线不作为通配符", FindingQuery{File: "a_cpp"}, 0},
+		{"严重级别", FindingQuery{Severities: []string{models.SeverityHigh}}, 8},
+		{"最低严重级别", FindingQuery{MinSeverity: models.SeverityMedium}, 16},
+		{"文本中的百分号按字面匹配", FindingQuery{Text: "%"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.ReportID = report.ID
+			_, total, err := repo.Find(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.want {
+				t.Errorf("total = %d, want %d", total, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountGate(t *testing.T) {
+	db := newTestDB(t)
+	report := &models.Report{SchemaVersion: models.ReportSchemaVersion, MD5Low32: "gate"}
+	add := func(severity string, confidence float64, status string) {
+		report.Findings = append(report.Findings, models.Finding{
+			File:       "a.cpp",
+			Rule:       "规则1",
+			Severity:   severity,
+			Rank:       models.SeverityRank(severity),
+			Status:     status,
+			Confidence: confidence,
+		})
+	}
+	add(models.SeverityHigh, 1, models.FindingOpen)
+	add(models.SeverityMedium, 2.0/3, models.FindingAccepted)
+	add(models.SeverityMedium, 0.5, models.FindingOpen)
+	add(models.SeverityLow, 1, models.FindingOpen)
+	add(models.SeverityHigh, 1, models.FindingFalsePositive)
+	add(models.SeverityHigh, 1, models.FindingWontFix)
+	if err := (&GormReports{DB: db}).Create(report); err != nil {
+		t.Fatal(err)
+	}
+	repo := &GormFindings{DB: db}
+
+	tests := []struct {
+		name          string
+		minSeverity   string
+		minConfidence float64
+		want          int64
+	}{
+		{"没有阈值", "", 0, 4},
+		{"级别恰好等于阈值", models.SeverityMedium, 0, 3},
+		{"最高级别", models.SeverityHigh, 0, 1},
+		{"置信度恰好等于阈值", "", 2.0 / 3, 3},
+		{"置信度略高于阈值", "", 0.67, 2},
+		{"两个阈值", models.SeverityMedium, 0.5, 3},
+		{"两个阈值都需满足", models.SeverityMedium, 0.6, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.CountGate(report.ID, tt.minSeverity, tt.minConfidence)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CountGate(%q, %v) = %d, want %d", tt.minSeverity, tt.minConfidence, got, tt.want)
+			}
+			// 与报告重新统计的结果一致
+			report.MinSeverity, report.MinConfidence = tt.minSeverity, tt.minConfidence
+			report.Recount()
+			if int64(report.GateIssues) != got {
+				t.Errorf("Recount gate issues %d, CountGate %d", report.GateIssues, got)
+			}
+		})
+	}
+}
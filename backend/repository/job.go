@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+type GormJobs struct {
+	DB *gorm.DB
+}
+
+func (r *GormJobs) Create(job *models.ScanJob) error {
+	return r.DB.Create(job).Error
+}
+
+func (r *GormJobs) Get(id uint) (*models.ScanJob, error) {
+	var job models.ScanJob
+	if err := r.DB.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &job, nil
+}
+
+func (r *GormJobs) LatestDone(md5Low32 string) (*models.ScanJob, error) {
+	var job models.ScanJob
+	if err := r.DB.Where("md5_low32 = ? AND status = ?", md5Low32, models.JobDone).
+		Order("id desc").First(&job).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &job, nil
+}
+
+func (r *GormJobs) Update(job *models.ScanJob, fields map[string]interface{}) error {
+	return r.DB.Model(job).Updates(fields).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"standardizer/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LLM 调用用量统计的分组字段
+var llmCallGroups = map[string]string{
+	"job":      "llm_calls.job_id",
+	"user":     "scan_jobs.user_name",
+	"rule_set": "llm_calls.rule_set",
+	"model":    "llm_calls.model",
+}
+
+// 是否支持按该字段分组统计
+func ValidLLMCallGroup(groupBy string) bool {
+	_, ok := llmCallGroups[groupBy]
+	return ok
+}
+
+// LLM 调用用量统计的分组和过滤条件，空值表示不过滤
+type LLMCallQuery struct {
+	GroupBy string
+	JobID   string
+	User    string
+	RuleSet string
+	Model   string
+	Since   time.Time
+}
+
+// 一组 LLM 调用的用量
+type LLMCallStats struct {
+	GroupKey         string  `json:"key"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	WaitMs           int64   `json:"wait_ms"`
+	AvgWaitMs        float64 `json:"avg_wait_ms"`
+	LatencyMs        int64   `json:"latency_ms"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	Retries          int64   `json:"retries"`
+	Aborted          int64   `json:"aborted"`
+	Failed           int64   `json:"failed"`
+}
+
+type GormLLMCalls struct {
+	DB *gorm.DB
+}
+
+func (r *GormLLMCalls) Create(call *models.LLMCall) error {
+	return r.DB.Create(call).Error
+}
+
+func (r *GormLLMCalls) Stats(q LLMCallQuery) ([]LLMCallStats, error) {
+	column := llmCallGroups[q.GroupBy]
+	query := r.DB.Table("llm_calls").
+		Joins("LEFT JOIN scan_jobs ON scan_jobs.id = llm_calls.job_id").
+		Select(column+` AS group_key,
+			COUNT(*) AS calls,
+			SUM(llm_calls.prompt_tokens) AS prompt_tokens,
+			SUM(llm_calls.completion_tokens) AS completion_tokens,
+			SUM(llm_calls.wait_ms) AS wait_ms,
+			AVG(llm_calls.wait_ms) AS avg_wait_ms,
+			SUM(llm_calls.latency_ms) AS latency_ms,
+			AVG(llm_calls.latency_ms) AS avg_latency_ms,
+			SUM(llm_calls.retries) AS retries,
+			SUM(CASE WHEN llm_calls.outcome = ? THEN 1 ELSE 0 END) AS aborted,
+			SUM(CASE WHEN llm_calls.outcome = ? THEN 1 ELSE 0 END) AS failed`,
+			models.CallAborted, models.CallFailed).
+		Group(column).
+		Order(column)
+	if q.JobID != "" {
+		query = query.Where("llm_calls.job_id = ?", q.JobID)
+	}
+	if q.User != "" {
+		query = query.Where("scan_jobs.user_name = ?", q.User)
+	}
+	if q.RuleSet != "" {
+		query = query.Where("llm_calls.rule_set = ?", q.RuleSet)
+	}
+	if q.Model != "" {
+		query = query.Where("llm_calls.model = ?", q.Model)
+	}
+	if !q.Since.IsZero() {
+		query = query.Where("llm_calls.created_at >= ?", q.Since)
+	}
+
+	var stats []LLMCallStats
+	err := query.Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"standardizer/models"
+	"testing"
+	"time"
+)
+
+func TestLLMCallStats(t *testing.T) {
+	db := newTestDB(t)
+	jobs := &GormJobs{DB: db}
+	calls := &GormLLMCalls{DB: db}
+
+	alice := &models.ScanJob{UserName: "alice", Status: models.JobDone}
+	bob := &models.ScanJob{UserName: "bob", Status: models.JobDone}
+	for _, job := range []*models.ScanJob{alice, bob} {
+		if err := jobs.Create(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, call := range []*models.LLMCall{
+		{JobID: alice.ID, Model: "qwen", RuleSet: "gjb", PromptTokens: 100, CompletionTokens: 10, WaitMs: 0, LatencyMs: 200, Outcome: models.CallOK},
+		{JobID: alice.ID, Model: "qwen", RuleSet: "gjb", PromptTokens: 300, CompletionTokens: 30, WaitMs: 40, LatencyMs: 400, Retries: 2, Outcome: models.CallFailed},
+		{JobID: alice.ID, Model: "deepseek", RuleSet: "misra", PromptTokens: 50, CompletionTokens: 500, LatencyMs: 900, Outcome: models.CallAborted},
+		{JobID: bob.ID, Model: "qwen", RuleSet: "gjb", PromptTokens: 80, CompletionTokens: 8, LatencyMs: 100, Outcome: models.CallOK},
+		{JobID: bob.ID, Model: "qwen", RuleSet: "gjb", PromptTokens: 1000, CompletionTokens: 100, Outcome: models.CallOK, CreatedAt: old},
+	} {
+		if err := calls.Create(call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query LLMCallQuery
+		want  []LLMCallStats
+	}{
+		{
+			name:  "按用户",
+			query: LLMCallQuery{GroupBy: "user"},
+			want: []LLMCallStats{
+				{GroupKey: "alice", Calls: 3, PromptTokens: 450, CompletionTokens: 540, WaitMs: 40, AvgWaitMs: 40.0 / 3, LatencyMs: 1500, AvgLatencyMs: 500, Retries: 2, Aborted: 1, Failed: 1},
+				{GroupKey: "bob", Calls: 2, PromptTokens: 1080, CompletionTokens: 108, LatencyMs: 100, AvgLatencyMs: 50},
+			},
+		},
+		{
+			name:  "按任务并过滤模型",
+			query: LLMCallQuery{GroupBy: "job", Model: "qwen"},
+			want: []LLMCallStats{
+				{GroupKey: "1", Calls: 2, PromptTokens: 400, CompletionTokens: 40, WaitMs: 40, AvgWaitMs: 20, LatencyMs: 600, AvgLatencyMs: 300, Retries: 2, Failed: 1},
+				{GroupKey: "2", Calls: 2, PromptTokens: 1080, CompletionTokens: 108, LatencyMs: 100, AvgLatencyMs: 50},
+			},
+		},
+		{
+			name:  "按规则集并过滤用户和时间",
+			query: LLMCallQuery{GroupBy: "rule_set", User: "bob", Since: time.Now().Add(-time.Hour)},
+			want:  []LLMCallStats{{GroupKey: "gjb", Calls: 1, PromptTokens: 80, CompletionTokens: 8, LatencyMs: 100, AvgLatencyMs: 100}},
+		},
+		{
+			name:  "按模型并过滤任务",
+			query: LLMCallQuery{GroupBy: "model", JobID: "1", RuleSet: "misra"},
+			want:  []LLMCallStats{{GroupKey: "deepseek", Calls: 1, PromptTokens: 50, CompletionTokens: 500, LatencyMs: 900, AvgLatencyMs: 900, Aborted: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !ValidLLMCallGroup(tt.query.GroupBy) {
+				t.Fatalf("group %q not valid", tt.query.GroupBy)
+			}
+			got, err := calls.Stats(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if diff := got[i].AvgWaitMs - tt.want[i].AvgWaitMs; diff > 1e-9 || diff < -1e-9 {
+					t.Errorf("row %d avg_wait_ms = %v, want %v", i, got[i].AvgWaitMs, tt.want[i].AvgWaitMs)
+				}
+				got[i].AvgWaitMs = tt.want[i].AvgWaitMs
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if ValidLLMCallGroup("file") {
+		t.Error("file accepted as a group")
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+type GormReports struct {
+	DB *gorm.DB
+}
+
+func (r *GormReports) Create(report *models.Report) error {
+	// 检查发现较多时分批写入
+	return r.DB.Session(&gorm.Session{CreateBatchSize: 500}).Create(report).Error
+}
+
+func (r *GormReports) Get(id uint, withFindings bool) (*models.Report, error) {
+	var report models.Report
+	if err := r.preload(withFindings).Where("id = ?", id).First(&report).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &report, nil
+}
+
+func (r *GormReports) Latest(md5Low32 string, withFindings bool) (*models.Report, error) {
+	// 旧版本遗留的报告只有文本内容，没有结构版本和检查发现，不能作为扫描结果返回
+	var report models.Report
+	if err := r.preload(withFindings).Where("md5_low32 = ? AND schema_version >= ?", md5Low32, 1).Order("id desc").First(&report).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &report, nil
+}
+
+// 读取报告时同时读取检查发现、修复及屏蔽注释
+func (r *GormReports) preload(withFindings bool) *gorm.DB {
+	if !withFindings {
+		return r.DB
+	}
+	return r.DB.Preload("Findings").Preload("Findings.Fix").Preload("Suppressions")
+}
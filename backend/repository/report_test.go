@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"standardizer/models"
+	"testing"
+)
+
+func TestReportsLatest(t *testing.T) {
+	db := newTestDB(t)
+	repo := &GormReports{DB: db}
+
+	// 旧版本遗留的报告没有结构版本，即使更新也不返回
+	if err := db.Exec("INSERT INTO reports (schema_version, md5_low32) VALUES (0, 'abc')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Latest("abc", false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("only legacy report: got %v, want ErrNotFound", err)
+	}
+
+	older := &models.Report{SchemaVersion: models.ReportSchemaVersion, MD5Low32: "abc", Title: "older"}
+	newer := &models.Report{
+		SchemaVersion: models.ReportSchemaVersion,
+		MD5Low32:      "abc",
+		Title:         "newer",
+		Findings: []models.Finding{
+			{File: "a.cpp", Line: 3, Rule: "规则3", Fix: &models.Fix{StartLine: 3, EndLine: 3, Replacement: "int *p = nullptr;", Applicable: true}},
+			{File: "a.cpp", Line: 5, Rule: "规则2"},
+		},
+		Suppressions: []models.Suppression{
+			{File: "a.cpp", Line: 8, Comment: 7, Scope: "line", Rules: "规则3", Reason: "由调用方初始化", Matched: 1},
+		},
+	}
+	for _, r := range []*models.Report{older, newer} {
+		if err := repo.Create(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO reports (schema_version, md5_low32) VALUES (NULL, 'abc')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.Latest("abc", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != newer.ID || len(got.Findings) != 0 {
+		t.Errorf("got report %d with %d findings, want %d without findings", got.ID, len(got.Findings), newer.ID)
+	}
+
+	got, err = repo.Latest("abc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(got.Findings))
+	}
+	var fixes int
+	for _, f := range got.Findings {
+		if f.Fix != nil {
+			fixes++
+			if f.Fix.Replacement != "int *p = nullptr;" {
+				t.Errorf("fix replacement %q", f.Fix.Replacement)
+			}
+		}
+	}
+	if fixes != 1 {
+		t.Errorf("got %d fixes, want 1", fixes)
+	}
+	// HTML 报告列出屏蔽注释及其理由
+	if len(got.Suppressions) != 1 || got.Suppressions[0].Reason != "由调用方初始化" || got.Suppressions[0].Rules != "规则3" {
+		t.Errorf("got suppressions %+v, want the saved suppression", got.Suppressions)
+	}
+
+	if _, err := repo.Latest("missing", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing md5: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestJobsLatestDone(t *testing.T) {
+	db := newTestDB(t)
+	repo := &GormJobs{DB: db}
+
+	if _, err := repo.LatestDone("abc"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("no jobs: got %v, want ErrNotFound", err)
+	}
+	jobs := []*models.ScanJob{
+		{MD5Low32: "abc", Status: models.JobDone, FilePath: "first"},
+		{MD5Low32: "abc", Status: models.JobDone, FilePath: "second"},
+		{MD5Low32: "abc", Status: models.JobFailed, FilePath: "failed"},
+		{MD5Low32: "abc", Status: models.JobRunning, FilePath: "running"},
+		{MD5Low32: "other", Status: models.JobDone, FilePath: "other"},
+	}
+	for _, job := range jobs {
+		if err := repo.Create(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.LatestDone("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FilePath != "second" {
+		t.Errorf("got job %q, want the latest done job", got.FilePath)
+	}
+
+	// 更新状态后最近完成的任务随之变化
+	if err := repo.Update(jobs[3], map[string]interface{}{"status": models.JobDone}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = repo.LatestDone("abc"); err != nil || got.FilePath != "running" {
+		t.Errorf("after update got %v, %v, want running", got, err)
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"errors"
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// 查询的记录不存在
+	ErrNotFound = errors.New("记录不存在")
+	// 人工判定的状态与检查发现当前状态相同
+	ErrSameStatus = errors.New("检查发现已处于该状态")
+)
+
+// 用户
+type UserRepository interface {
+	Create(user *models.User) error
+	FindByName(name string) (*models.User, error)
+}
+
+// 扫描任务
+type JobRepository interface {
+	Create(job *models.ScanJob) error
+	Get(id uint) (*models.ScanJob, error)
+	// 文件最近一次完成的扫描任务
+	LatestDone(md5Low32 string) (*models.ScanJob, error)
+	Update(job *models.ScanJob, fields map[string]interface{}) error
+}
+
+// 报告
+type ReportRepository interface {
+	// 保存报告及其检查发现、屏蔽注释和修复
+	Create(report *models.Report) error
+	// withFindings 为 true 时同时读取检查发现、修复及屏蔽注释
+	Get(id uint, withFindings bool) (*models.Report, error)
+	// 文件最近一次生成的报告，不含旧版本遗留的报告，withFindings 为 true 时同时读取检查发现及修复
+	Latest(md5Low32 string, withFindings bool) (*models.Report, error)
+}
+
+// 检查发现及人工判定
+type FindingRepository interface {
+	Get(id uint) (*models.Finding, error)
+	// 报告的全部检查发现及修复，按文件和行号排序
+	ListByReport(reportID uint) ([]models.Finding, error)
+	// 按过滤条件、排序和游标查询，同时返回满足过滤条件的总数
+	Find(query FindingQuery) ([]models.Finding, int64, error)
+	// 达到门禁阈值的未屏蔽问题数
+	CountGate(reportID uint, minSeverity string, minConfidence float64) (int64, error)
+	// 记录人工判定，更新检查发现的状态并重新统计报告中的问题数
+	Triage(id uint, triage *models.FindingTriage) (*models.Finding, error)
+	// 检查发现的判定历史，包括以往扫描中同一指纹的判定，最近的在前
+	TriageHistory(finding *models.Finding) ([]models.FindingTriage, error)
+	// 各指纹最近一次人工判定
+	LatestTriages(fingerprints []string) (map[string]*models.FindingTriage, error)
+	// 按提示词模板版本统计 LLM 发现的问题及人工判定结果，ruleSet 和 rules 为空时不过滤
+	PromptStats(ruleSet string, rules []string) ([]PromptStats, error)
+}
+
+// LLM 调用记录及用量统计
+type LLMCallRepository interface {
+	Create(call *models.LLMCall) error
+	// 按 query.GroupBy 分组汇总用量，分组字段须先经 ValidLLMCallGroup 校验
+	Stats(query LLMCallQuery) ([]LLMCallStats, error)
+}
+
+// 文章
+type ArticleRepository interface {
+	Create(article *models.Article) error
+	List() ([]models.Article, error)
+	Get(id uint) (*models.Article, error)
+}
+
+// 汇率
+type ExchangeRateRepository interface {
+	Create(rate *models.ExchangeRate) error
+	List() ([]models.ExchangeRate, error)
+}
+
+// 全部仓储
+type Repositories struct {
+	Users    UserRepository
+	Jobs     JobRepository
+	Reports  ReportRepository
+	Findings FindingRepository
+	Calls    LLMCallRepository
+	Articles ArticleRepository
+	Rates    ExchangeRateRepository
+}
+
+// 基于 GORM 的仓储，MySQL 和 SQLite 均可使用
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:    &GormUsers{DB: db},
+		Jobs:     &GormJobs{DB: db},
+		Reports:  &GormReports{DB: db},
+		Findings: &GormFindings{DB: db},
+		Calls:    &GormLLMCalls{DB: db},
+		Articles: &GormArticles{DB: db},
+		Rates:    &GormExchangeRates{DB: db},
+	}
+}
+
+// 将 GORM 的记录不存在错误转换为 ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
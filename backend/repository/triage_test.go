@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+	"standardizer/models"
+	"testing"
+)
+
+// 保存两份同一文件的报告，检查发现的指纹相同
+func createTriageReports(t *testing.T, repo *GormReports) (*models.Report, *models.Report) {
+	t.Helper()
+	var reports []*models.Report
+	for i := 0; i < 2; i++ {
+		report := &models.Report{
+			SchemaVersion: models.ReportSchemaVersion,
+			MD5Low32:      "abc",
+			MinSeverity:   models.SeverityHigh,
+			Files:         []models.FileResult{{File: "a.cpp"}},
+			Findings: []models.Finding{
+				{File: "a.cpp", Line: 1, Rule: "规则2", Severity: models.SeverityHigh, Rank: 3, Status: models.FindingOpen, Confidence: 1, Fingerprint: "fp-cast"},
+				{File: "a.cpp", Line: 2, Rule: "规则1", Severity: models.SeverityMedium, Rank: 2, Status: models.FindingOpen, Confidence: 1, Fingerprint: "fp-index"},
+			},
+		}
+		report.Recount()
+		if err := repo.Create(report); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+	return reports[0], reports[1]
+}
+
+func TestFindingsTriage(t *testing.T) {
+	db := newTestDB(t)
+	reports := &GormReports{DB: db}
+	repo := &GormFindings{DB: db}
+	first, second := createTriageReports(t, reports)
+	if first.GateIssues != 1 || first.Passed {
+		t.Fatalf("before triage gate_issues=%d passed=%v", first.GateIssues, first.Passed)
+	}
+
+	target := first.Findings[0]
+	finding, err := repo.Triage(target.ID, &models.FindingTriage{Status: models.FindingFalsePositive, Justification: "已确认安全", UserName: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finding.Status != models.FindingFalsePositive || finding.TriagedBy != "alice" || finding.TriagedAt == nil {
+		t.Errorf("got finding %+v", finding)
+	}
+
+	// 报告重新统计，高危问题被屏蔽后通过门禁
+	got, err := reports.Get(first.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 下载报告时读取到判定后的状态
+	if suppressed := got.SuppressedFindings(); len(suppressed) != 1 || suppressed[0].Justification != "已确认安全" {
+		t.Errorf("suppressed findings %+v, want the triaged finding", suppressed)
+	}
+	if got.TotalIssues != 1 || got.Suppressed != 1 || got.GateIssues != 0 || !got.Passed {
+		t.Errorf("report total=%d suppressed=%d gate_issues=%d passed=%v, want 1 1 0 true", got.TotalIssues, got.Suppressed, got.GateIssues, got.Passed)
+	}
+	if len(got.Files) != 1 || got.Files[0].FindingCount != 1 {
+		t.Errorf("files %+v, want a.cpp with 1 finding", got.Files)
+	}
+	// 另一份报告不受影响
+	if other, err := reports.Get(second.ID, false); err != nil || other.Suppressed != 0 {
+		t.Errorf("other report suppressed=%d, %v", other.Suppressed, err)
+	}
+
+	if _, err := repo.Triage(target.ID, &models.FindingTriage{Status: models.FindingFalsePositive, Justification: "重复"}); !errors.Is(err, ErrSameStatus) {
+		t.Errorf("same status: got %v, want ErrSameStatus", err)
+	}
+	if _, err := repo.Triage(9999, &models.FindingTriage{Status: models.FindingAccepted}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing finding: got %v, want ErrNotFound", err)
+	}
+
+	// 重新打开后恢复为未通过
+	if _, err := repo.Triage(target.ID, &models.FindingTriage{Status: models.FindingOpen, UserName: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = reports.Get(first.ID, false); err != nil || got.GateIssues != 1 || got.Passed {
+		t.Errorf("after reopen gate_issues=%d passed=%v, %v", got.GateIssues, got.Passed, err)
+	}
+
+	// 判定历史包括以往扫描中同一指纹的判定，最近的在前
+	history, err := repo.TriageHistory(&second.Findings[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].UserName != "bob" || history[0].FromStatus != models.FindingFalsePositive {
+		t.Errorf("history %+v, want 2 entries with the reopen first", history)
+	}
+}
+
+func TestLatestTriages(t *testing.T) {
+	db := newTestDB(t)
+	repo := &GormFindings{DB: db}
+	first, _ := createTriageReports(t, &GormReports{DB: db})
+
+	latest, err := repo.LatestTriages(nil)
+	if err != nil || len(latest) != 0 {
+		t.Fatalf("no fingerprints: got %v, %v", latest, err)
+	}
+
+	steps := []struct {
+		finding models.Finding
+		status  string
+	}{
+		{first.Findings[0], models.FindingFalsePositive},
+		{first.Findings[0], models.FindingAccepted},
+		{first.Findings[1], models.FindingWontFix},
+	}
+	for _, step := range steps {
+		if _, err := repo.Triage(step.finding.ID, &models.FindingTriage{Status: step.status, Justification: "理由"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err = repo.LatestTriages([]string{"fp-cast", "fp-index", "fp-unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 2 {
+		t.Fatalf("got %d fingerprints, want 2", len(latest))
+	}
+	if latest["fp-cast"].Status != models.FindingAccepted {
+		t.Errorf("fp-cast status %q, want the latest triage %q", latest["fp-cast"].Status, models.FindingAccepted)
+	}
+	if latest["fp-index"].Status != models.FindingWontFix {
+		t.Errorf("fp-index status %q, want %q", latest["fp-index"].Status, models.FindingWontFix)
+	}
+}
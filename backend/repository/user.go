@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"standardizer/models"
+
+	"gorm.io/gorm"
+)
+
+type GormUsers struct {
+	DB *gorm.DB
+}
+
+func (r *GormUsers) Create(user *models.User) error {
+	return r.DB.Create(user).Error
+}
+
+func (r *GormUsers) FindByName(name string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.Where("name = ?", name).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
@@ -9,7 +9,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter() *gin.Engine {
+// 按注入的处理器注册路由
+func SetupRouter(h *controllers.Handlers) *gin.Engine {
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
@@ -23,28 +24,40 @@ func SetupRouter() *gin.Engine {
 
 	auth := r.Group("/api/auth")
 	{
-		auth.POST("/login", controllers.Login)
-		auth.POST("/register", controllers.Register)
+		auth.POST("/login", h.Auth.Login)
+		auth.POST("/register", h.Auth.Register)
 
 	}
 	api := r.Group("/api")
-	api.GET("/exchangeRates", controllers.GetExchangeRates)
+	api.GET("/exchangeRates", h.ExchangeRate.GetExchangeRates)
 
 	api.Use(middlewares.AuthMiddleWare())
 	{
 
-		api.POST("/exchangeRates", controllers.CreateExchangeRate)
-		api.POST("/articles", controllers.CreateArticle)
-		api.GET("/articles", controllers.GetArticles)
-		api.GET("/articles/:id", controllers.GetArticleByID)
+		api.POST("/exchangeRates", h.ExchangeRate.CreateExchangeRate)
+		api.POST("/articles", h.Article.CreateArticle)
+		api.GET("/articles", h.Article.GetArticles)
+		api.GET("/articles/:id", h.Article.GetArticleByID)
 
-		api.POST("/articles/:id/like", controllers.LikeArticle)
-		api.GET("/articles/:id/like", controllers.GetArticleLikes)
+		api.POST("/articles/:id/like", h.Article.LikeArticle)
+		api.GET("/articles/:id/like", h.Article.GetArticleLikes)
 
-		api.GET("/get-response", controllers.GetResponse)
-		api.GET("/check-report", controllers.CheckReport)
+		api.GET("/get-response", h.Scan.GetResponse)
+		api.GET("/check-report", h.Scan.CheckReport)
+		api.GET("/jobs/:id", h.Scan.GetJob)
+		api.GET("/jobs/:id/progress", h.Scan.GetJobProgress)
 		api.POST("/upload", controllers.UploadFile)
-		api.GET("/download-report", controllers.DownloadReport)
+		api.GET("/download-report", h.Scan.DownloadReport)
+		api.GET("/download-report-html", h.Report.DownloadHTMLReport)
+		api.GET("/reports/:id/findings", h.Report.GetFindings)
+		api.GET("/reports/:id/patch", h.Report.DownloadPatch)
+		api.GET("/reports/:id/fixed-file", h.Report.DownloadFixedFile)
+		api.POST("/findings/:id/triage", h.Report.TriageFinding)
+		api.GET("/findings/:id/triage", h.Report.GetFindingTriage)
+		api.GET("/prompts", h.Report.GetPrompts)
+		api.GET("/prompts/stats", h.Report.GetPromptStats)
+		api.GET("/llm-calls/stats", h.Scan.GetLLMCallStats)
+		api.GET("/llm/providers", h.Scan.GetLLMProviders)
 	}
 
 	return r
@@ -4,7 +4,11 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -85,7 +89,15 @@ func Atoi(s string) int {
 	return i
 }
 
+// 文件内容的 md5；目录按其中各文件的相对路径和内容计算，内容相同的目录得到相同的值。出错时返回空字符串
 func CalcMd5(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return calcDirMd5(path)
+	}
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return ""
@@ -94,3 +106,90 @@ func CalcMd5(path string) string {
 	md5Low32 := hex.EncodeToString(md5Hash[:])[:32]
 	return md5Low32
 }
+
+// 按文件名顺序遍历目录，依次计入每个文件的相对路径和内容 md5，不跟随符号链接，跳过 .git
+func calcDirMd5(root string) string {
+	h := md5.New()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%x\n", filepath.ToSlash(rel), md5.Sum(content))
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LIKE 语句使用的转义字符，MySQL 与 SQLite 均支持
+const LikeEscape = "!"
+
+// 转义 LIKE 语句中的特殊字符
+func EscapeLike(s string) string {
+	r := strings.NewReplacer(LikeEscape, LikeEscape+LikeEscape, "%", LikeEscape+"%", "_", LikeEscape+"_")
+	return r.Replace(s)
+}
+
+// 将文件通配符转换为 LIKE 模式：* 与 ** 匹配任意字符，? 匹配单个字符
+func GlobToLike(pattern string) string {
+	var b strings.Builder
+	lastStar := false
+	for _, r := range EscapeLike(pattern) {
+		switch r {
+		case '*':
+			// 连续的 * 只保留一个 %
+			if !lastStar {
+				b.WriteRune('%')
+			}
+		case '?':
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+		lastStar = r == '*'
+	}
+	return b.String()
+}
+
+// 文件通配符匹配，路径以 / 分隔，** 匹配任意层目录
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// ** 可以匹配零个或多个目录
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{"100%", "100!%"},
+		{"a_b", "a!_b"},
+		{"wow!", "wow!!"},
+		{"!%_", "!!!%!_"},
+	}
+	for _, tt := range tests {
+		if got := EscapeLike(tt.in); got != tt.want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGlobToLike(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"src/*.cpp", "src/%.cpp"},
+		{"src/**/*.cpp", "src/%/%.cpp"},
+		{"**", "%"},
+		{"a?.h", "a_.h"},
+		{"my_file.c", "my!_file.c"},
+		{"50%.c", "50!%.c"},
+	}
+	for _, tt := range tests {
+		if got := GlobToLike(tt.in); got != tt.want {
+			t.Errorf("GlobToLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCalcMd5Directory(t *testing.T) {
+	write := func(root string, files map[string]string) string {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return root
+	}
+	project := map[string]string{"src/a.cpp": "int a;", "src/b.cpp": "int b;", ".standardizer.yml": "rule_set: gjb"}
+	a := CalcMd5(write(t.TempDir(), project))
+	if a == "" || len(a) != 32 {
+		t.Fatalf("got %q, want a 32 character hash", a)
+	}
+	if b := CalcMd5(write(t.TempDir(), project)); b != a {
+		t.Errorf("same tree got %q and %q", a, b)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{name: "文件内容不同", files: map[string]string{"src/a.cpp": "int a = 1;", "src/b.cpp": "int b;", ".standardizer.yml": "rule_set: gjb"}},
+		{name: "文件名不同", files: map[string]string{"src/a.cpp": "int a;", "src/c.cpp": "int b;", ".standardizer.yml": "rule_set: gjb"}},
+		{name: "项目配置不同", files: map[string]string{"src/a.cpp": "int a;", "src/b.cpp": "int b;", ".standardizer.yml": "rule_set: misra"}},
+		{name: "空目录", files: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalcMd5(write(t.TempDir(), tt.files)); got == a || got == "" {
+				t.Errorf("got %q, want a different non-empty hash", got)
+			}
+		})
+	}
+
+	// .git 中的内容不影响结果
+	withGit := write(t.TempDir(), project)
+	write(withGit, map[string]string{".git/HEAD": "ref: refs/heads/main"})
+	if got := CalcMd5(withGit); got != a {
+		t.Errorf("tree with .git got %q, want %q", got, a)
+	}
+	if got := CalcMd5(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Errorf("missing path got %q", got)
+	}
+}